@@ -0,0 +1,126 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// ADPCMSample reads a sign bit followed by <bits>-1 magnitude bits, up to
+// 31 bits overall, whose most significant magnitude bit is implicit & set.
+// A field s|m decodes to ±(1<<(bits-1) | m), negative when s is set, so the
+// decoded magnitude is always in [1<<(bits-1), 1<<bits).
+// This is the plain sign & implied-one magnitude convention only: it does
+// not track any predictor nor step size like IMA or MS ADPCM decoders do.
+func (r *Reader) ADPCMSample(bits uint) int32 {
+	sign := r.Bit()
+	val := int32(1<<(bits-1) | r.Uint32(bits-1))
+	if sign {
+		return -val
+	}
+	return val
+}
+
+// PutADPCMSample writes a sign bit followed by the <bits>-1 low magnitude
+// bits of <val>, dropping its implicit most significant magnitude bit.
+func (w *Writer) PutADPCMSample(bits uint, val int32) {
+	w.PutBit(val < 0)
+	if val < 0 {
+		val = -val
+	}
+	w.PutUint32(bits-1, uint32(val))
+}
+
+// OffsetBinary reads up to 32 bits stored in offset binary, where the
+// midpoint 1<<(bits-1) encodes zero.
+func (r *Reader) OffsetBinary(bits uint) int32 {
+	return int32(int64(r.Uint32(bits)) - 1<<(bits-1))
+}
+
+// PutOffsetBinary writes up to 32 bits in offset binary, where the
+// midpoint 1<<(bits-1) encodes zero.
+func (w *Writer) PutOffsetBinary(bits uint, val int32) {
+	w.PutUint32(bits, uint32(int64(val)+1<<(bits-1)))
+}
+
+// G.711 companding, following the reference implementation by Sun
+// Microsystems.
+
+const (
+	muLawBias = 0x84
+	muLawClip = 8159
+)
+
+var (
+	muLawSegments = [8]int32{0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF}
+	aLawSegments  = [8]int32{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+)
+
+func segment(val int32, segments *[8]int32) int {
+	for i, end := range segments {
+		if val <= end {
+			return i
+		}
+	}
+	return len(segments)
+}
+
+// MuLaw reads an 8-bit µ-law sample and expands it to 16-bit linear PCM.
+func (r *Reader) MuLaw() int16 {
+	u := ^r.Byte()
+	t := (int32(u&0x0F)<<3 + muLawBias) << (u & 0x70 >> 4)
+	if u&0x80 != 0 {
+		return int16(muLawBias - t)
+	}
+	return int16(t - muLawBias)
+}
+
+// PutMuLaw compresses a 16-bit linear PCM sample to µ-law and writes it.
+func (w *Writer) PutMuLaw(val int16) {
+	pcm := int32(val) >> 2
+	mask := byte(0xFF)
+	if pcm < 0 {
+		pcm = -pcm
+		mask = 0x7F
+	}
+	pcm = min(pcm, muLawClip) + muLawBias>>2
+	seg := segment(pcm, &muLawSegments)
+	if seg >= 8 {
+		w.PutByte(0x7F ^ mask)
+		return
+	}
+	w.PutByte((byte(seg)<<4 | byte(pcm>>(seg+1)&0x0F)) ^ mask)
+}
+
+// ALaw reads an 8-bit A-law sample and expands it to 16-bit linear PCM.
+func (r *Reader) ALaw() int16 {
+	a := r.Byte() ^ 0x55
+	t := int32(a&0x0F) << 4
+	switch seg := a & 0x70 >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t = (t + 0x108) << (seg - 1)
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// PutALaw compresses a 16-bit linear PCM sample to A-law and writes it.
+func (w *Writer) PutALaw(val int16) {
+	pcm := int32(val) >> 3
+	mask := byte(0xD5)
+	if pcm < 0 {
+		pcm = -pcm - 1
+		mask = 0x55
+	}
+	seg := segment(pcm, &aLawSegments)
+	if seg >= 8 {
+		w.PutByte(0x7F ^ mask)
+		return
+	}
+	shift := max(seg, 1)
+	w.PutByte((byte(seg)<<4 | byte(pcm>>shift&0x0F)) ^ mask)
+}
@@ -0,0 +1,116 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestADPCMSample(t *testing.T) {
+	// 4-bit samples: s m2 m1 m0 with an implicit m3
+	r := NewReader([]byte{0x07, 0x8F, 0x30})
+	for _, v := range []int32{8, 15, -8, -15, 11, 8} {
+		expect(t, v, r.ADPCMSample(4))
+	}
+	buf := make([]byte, 32)
+	for bits := uint(1); bits <= 31; bits++ {
+		lo := int64(1) << (bits - 1)
+		var values []int32
+		for _, v := range []int64{lo, lo + lo/2, 2*lo - 1, -lo, -2*lo + 1} {
+			values = append(values, int32(v))
+		}
+		w := NewWriter(buf)
+		for _, v := range values {
+			w.PutADPCMSample(bits, v)
+		}
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		for _, v := range values {
+			expect(t, v, r.ADPCMSample(bits))
+		}
+	}
+}
+
+func TestOffsetBinary(t *testing.T) {
+	r := NewReader([]byte{0x08, 0xF7})
+	expect(t, int32(-8), r.OffsetBinary(4))
+	expect(t, int32(0), r.OffsetBinary(4))
+	expect(t, int32(7), r.OffsetBinary(4))
+	expect(t, int32(-1), r.OffsetBinary(4))
+	buf := make([]byte, 1<<10)
+	for bits := uint(1); bits <= 8; bits++ {
+		w := NewWriter(buf)
+		lo, hi := -int32(1)<<(bits-1), int32(1)<<(bits-1)-1
+		for v := lo; v <= hi; v++ {
+			w.PutOffsetBinary(bits, v)
+		}
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		for v := lo; v <= hi; v++ {
+			expect(t, uint32(v-lo), r.Peek().Uint32(bits))
+			expect(t, v, r.OffsetBinary(bits))
+		}
+	}
+	buf = make([]byte, 8)
+	w := NewWriter(buf)
+	w.PutOffsetBinary(32, -1<<31)
+	w.PutOffsetBinary(32, 1<<31-1)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}, buf)
+	r = NewReader(buf)
+	expect(t, int32(-1<<31), r.OffsetBinary(32))
+	expect(t, int32(1<<31-1), r.OffsetBinary(32))
+}
+
+func TestMuLaw(t *testing.T) {
+	r := NewReader([]byte{0xFF, 0x7F, 0x00, 0x80, 0xF0, 0x70})
+	for _, v := range []int16{0, 0, -32124, 32124, 120, -120} {
+		expect(t, v, r.MuLaw())
+	}
+	buf := make([]byte, 256)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	r = NewReader(buf)
+	out := make([]byte, 256)
+	w := NewWriter(out)
+	for range buf {
+		w.PutMuLaw(r.MuLaw())
+	}
+	expect(t, nil, w.Flush())
+	buf[0x7F] = 0xFF // negative zero
+	compare(t, buf, out)
+	w = NewWriter(out)
+	w.PutMuLaw(32767)
+	w.PutMuLaw(-32768)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{0x80, 0x00}, out[:2])
+}
+
+func TestALaw(t *testing.T) {
+	r := NewReader([]byte{0xD5, 0x55, 0xAA, 0x2A, 0xD4, 0x54})
+	for _, v := range []int16{8, -8, 32256, -32256, 24, -24} {
+		expect(t, v, r.ALaw())
+	}
+	buf := make([]byte, 256)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	r = NewReader(buf)
+	out := make([]byte, 256)
+	w := NewWriter(out)
+	for range buf {
+		w.PutALaw(r.ALaw())
+	}
+	expect(t, nil, w.Flush())
+	compare(t, buf, out)
+	w = NewWriter(out)
+	w.PutALaw(32767)
+	w.PutALaw(-32768)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{0xAA, 0x2A}, out[:2])
+}
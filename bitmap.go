@@ -0,0 +1,35 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/bits"
+)
+
+// Bitmap reads a presence bitmap of <n> bits and returns an iterator over
+// the indices of its set bits, in increasing order, where index 0 is the
+// first bit read.
+// The reader advances past the whole bitmap immediately, while the iterator
+// decodes it lazily 64 bits at a time.
+func (r *Reader) Bitmap(n uint) func() (index uint, ok bool) {
+	p := *r
+	r.Skip(n)
+	var word uint64
+	base, done := uint(0), uint(0)
+	return func() (uint, bool) {
+		for word == 0 {
+			if done >= n {
+				return 0, false
+			}
+			size := min(n-done, 64)
+			word = p.Uint64(size) << (64 - size)
+			base = done
+			done += size
+		}
+		lz := uint(bits.LeadingZeros64(word))
+		word &^= 1 << (63 - lz)
+		return base + lz, true
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	src := makeSource(32)
+	for _, skip := range []uint{0, 3, 17} {
+		for _, n := range []uint{0, 1, 13, 64, 65, 200} {
+			r := NewReader(src)
+			r.Skip(skip)
+			next := r.Bitmap(n)
+			expect(t, skip+n, r.At())
+			ref := NewReader(src)
+			ref.Skip(skip)
+			for i := uint(0); i < n; i++ {
+				if !ref.Bit() {
+					continue
+				}
+				idx, ok := next()
+				expect(t, true, ok)
+				expect(t, i, idx)
+			}
+			_, ok := next()
+			expect(t, false, ok)
+		}
+	}
+	r := NewReader([]byte{0x81, 0x00, 0x40})
+	next := r.Bitmap(24)
+	for _, want := range []uint{0, 7, 17} {
+		idx, ok := next()
+		expect(t, true, ok)
+		expect(t, want, idx)
+	}
+	_, ok := next()
+	expect(t, false, ok)
+}
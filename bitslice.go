@@ -0,0 +1,59 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// BitSliceReader decodes values stored bit-sliced, i.e. transposed into
+// bit planes: first bit 0 of every value, then bit 1 of every value & so on
+// up to the most significant plane.
+type BitSliceReader struct {
+	r      *Reader
+	planes uint
+	count  int
+}
+
+// NewBitSliceReader returns a new reader decoding <count> values of
+// <planes> bits, up to 32, from <r>.
+func NewBitSliceReader(r *Reader, planes uint, count int) BitSliceReader {
+	return BitSliceReader{
+		r:      r,
+		planes: planes,
+		count:  count,
+	}
+}
+
+// Decode reads all planes and returns the reconstructed values.
+func (b *BitSliceReader) Decode() []uint32 {
+	values := make([]uint32, b.count)
+	for p := uint(0); p < b.planes; p++ {
+		for i := range values {
+			values[i] |= b.r.Uint32(1) << p
+		}
+	}
+	return values
+}
+
+// BitSliceWriter encodes values bit-sliced, mirroring BitSliceReader.
+type BitSliceWriter struct {
+	w      *Writer
+	planes uint
+}
+
+// NewBitSliceWriter returns a new writer encoding values of <planes> bits,
+// up to 32, to <w>.
+func NewBitSliceWriter(w *Writer, planes uint) BitSliceWriter {
+	return BitSliceWriter{
+		w:      w,
+		planes: planes,
+	}
+}
+
+// Encode writes all planes of <values>.
+func (b *BitSliceWriter) Encode(values []uint32) {
+	for p := uint(0); p < b.planes; p++ {
+		for _, v := range values {
+			b.w.PutUint32(1, v>>p)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitSliceReader(t *testing.T) {
+	// values 5, 2, 7, 0 on 3 planes
+	// plane 0: 1 0 1 0
+	// plane 1: 0 1 1 0
+	// plane 2: 1 0 1 0
+	buf := []byte{0xA6, 0xA0}
+	r := NewReader(buf)
+	b := NewBitSliceReader(&r, 3, 4)
+	expect(t, []uint32{5, 2, 7, 0}, b.Decode())
+	expect(t, uint(12), r.At())
+}
+
+func TestBitSliceRoundTrip(t *testing.T) {
+	for planes := uint(0); planes <= 32; planes++ {
+		values := make([]uint32, 1+rand.Intn(100))
+		for i := range values {
+			values[i] = uint32(rand.Uint64() & (1<<planes - 1))
+		}
+		buf := make([]byte, len(values)*4+1)
+		w := NewWriter(buf)
+		bw := NewBitSliceWriter(&w, planes)
+		bw.Encode(values)
+		expect(t, int(planes)*len(values), w.Index())
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		br := NewBitSliceReader(&r, planes, len(values))
+		expect(t, values, br.Decode())
+	}
+}
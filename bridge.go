@@ -0,0 +1,50 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"io"
+	"math"
+)
+
+// NewBridgeWriter returns a new writer buffering up to <bufSize> bytes, at
+// least 8, which are sent to <dst> whenever the buffer fills up & on Flush.
+// Its output is unbounded so it never overflows. Only complete bytes are
+// sent, Flush keeping any partial byte & reporting ErrUnderflow like other
+// writers. The first error returned by <dst> is sticky & reported by Flush.
+func NewBridgeWriter(dst io.Writer, bufSize int) Writer {
+	return Writer{
+		dst:  make([]byte, max(bufSize, 8)),
+		room: 64,
+		end:  math.MaxInt,
+		out:  dst,
+	}
+}
+
+// drain sends every stored byte to the bridged writer.
+func (w *Writer) drain() {
+	if w.idx == 0 {
+		return
+	}
+	if w.err == nil {
+		_, w.err = w.out.Write(w.dst[:w.idx])
+	}
+	w.sent += w.idx
+	w.idx = 0
+}
+
+func (w *Writer) flushBridge() error {
+	for w.store(); w.fill >= 8; w.store() {
+		w.drain()
+	}
+	w.drain()
+	if w.err != nil {
+		return w.err
+	}
+	if w.fill != 0 {
+		return ErrUnderflow
+	}
+	return nil
+}
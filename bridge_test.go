@@ -0,0 +1,62 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBridgeWriter(t *testing.T) {
+	for _, size := range []int{0, 8, 9, 64, 4096} {
+		ref := make([]byte, 1024)
+		x := NewWriter(ref)
+		out := bytes.Buffer{}
+		w := NewBridgeWriter(&out, size)
+		src := makeSource(1024)
+		r := NewReader(src)
+		for _, bits := range []uint{1, 7, 13, 32, 64, 3, 24} {
+			for i := 0; i < 16; i++ {
+				v := r.Uint64(bits)
+				x.PutUint64(bits, v)
+				w.PutUint64(bits, v)
+				expect(t, x.Index(), w.Index())
+			}
+		}
+		// 16 * 144 bits is aligned
+		w.Write([]byte{0x12, 0x34})
+		x.Write([]byte{0x12, 0x34})
+		w.PutByte(0xAB)
+		x.PutByte(0xAB)
+		w.Fill(0xCD, 30)
+		x.Fill(0xCD, 30)
+		w.PutUint32(4, 0x5)
+		expect(t, ErrUnderflow, w.Flush())
+		w.PutUint32(4, 0xA)
+		expect(t, nil, w.Flush())
+		x.PutUint32(8, 0x5A)
+		expect(t, nil, x.Flush())
+		expect(t, x.Index(), w.Index())
+		compare(t, ref[:x.Index()/8], out.Bytes())
+	}
+}
+
+type failWriter struct{}
+
+var errFail = errors.New("fail")
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errFail
+}
+
+func TestBridgeWriterError(t *testing.T) {
+	w := NewBridgeWriter(failWriter{}, 8)
+	for i := 0; i < 4; i++ {
+		w.PutBe32(uint32(i))
+	}
+	expect(t, errFail, w.Flush())
+	expect(t, 128, w.Index())
+}
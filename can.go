@@ -0,0 +1,90 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// CAN signals are placed in a frame as described by DBC files: frame bits
+// are numbered from the least significant bit of each byte, bit 0 being
+// the low bit of byte 0 & bit 63 the high bit of byte 7. Signals are
+// random-access so they are read relative to the reader position, which
+// must be byte-aligned, without moving it.
+
+// canBit returns bit <pos> of the frame starting at the reader position.
+// Flags ErrOverflow past the reader end.
+func (r *Reader) canBit(pos uint) uint64 {
+	i := r.idx>>3 + pos>>3
+	if i<<3+8 > r.end {
+		r.err = ErrOverflow
+		return 0
+	}
+	return uint64(r.src[i] >> (pos & 7) & 1)
+}
+
+// IntelSignal reads a little-endian CAN signal of <length> bits, up to 64,
+// whose least significant bit is at frame bit <startBit>.
+func (r *Reader) IntelSignal(startBit, length uint) uint64 {
+	var val uint64
+	for i := uint(0); i < length; i++ {
+		val |= r.canBit(startBit+i) << i
+	}
+	return val
+}
+
+// MotorolaSignal reads a big-endian CAN signal of <length> bits, up to 64,
+// whose most significant bit is at frame bit <startBit>.
+func (r *Reader) MotorolaSignal(startBit, length uint) uint64 {
+	var val uint64
+	pos := startBit
+	for i := uint(0); i < length; i++ {
+		val = val<<1 | r.canBit(pos)
+		pos = nextMotorolaBit(pos)
+	}
+	return val
+}
+
+// nextMotorolaBit returns the frame bit following <pos> in a big-endian
+// signal, which continues at the high bit of the next byte.
+func nextMotorolaBit(pos uint) uint {
+	if pos&7 == 0 {
+		return pos + 15
+	}
+	return pos - 1
+}
+
+// putCANBit sets bit <pos> of the frame starting at the writer position to
+// the low bit of <val>.
+// Flags ErrOverflow past the writer end.
+func (w *Writer) putCANBit(pos uint, val uint64) {
+	i := w.idx + int(pos>>3)
+	if i<<3+8 > w.end || i >= len(w.dst) {
+		w.err = ErrOverflow
+		return
+	}
+	mask := byte(1) << (pos & 7)
+	w.dst[i] = w.dst[i]&^mask | byte(val&1)<<(pos&7)
+}
+
+// PutIntelSignal writes a little-endian CAN signal of <length> bits, up to
+// 64, whose least significant bit is at frame bit <startBit>.
+// Signals are written in place into the frame starting at the writer
+// position, which is flushed first & must be byte-aligned, without moving
+// it. Other frame bits are left untouched.
+func (w *Writer) PutIntelSignal(startBit, length uint, val uint64) {
+	w.store()
+	for i := uint(0); i < length; i++ {
+		w.putCANBit(startBit+i, val>>i)
+	}
+}
+
+// PutMotorolaSignal writes a big-endian CAN signal of <length> bits, up to
+// 64, whose most significant bit is at frame bit <startBit>.
+// Signals are written like in PutIntelSignal.
+func (w *Writer) PutMotorolaSignal(startBit, length uint, val uint64) {
+	w.store()
+	pos := startBit
+	for i := length; i > 0; i-- {
+		w.putCANBit(pos, val>>(i-1))
+		pos = nextMotorolaBit(pos)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+var canSignals = []struct {
+	intel    bool
+	start    uint
+	length   uint
+	expected uint64
+}{
+	{true, 0, 16, 0x3412},
+	{true, 12, 8, 0x63},
+	{true, 3, 1, 0},
+	{true, 4, 1, 1},
+	{true, 0, 64, 0xF0DEBC9A78563412},
+	{true, 63, 1, 1},
+	{false, 7, 16, 0x1234},
+	{false, 3, 8, 0x23},
+	{false, 23, 12, 0x567},
+	{false, 7, 64, 0x123456789ABCDEF0},
+	{false, 56, 1, 0},
+}
+
+var canFrame = []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}
+
+func TestCANSignals(t *testing.T) {
+	for _, v := range canSignals {
+		r := NewReader(canFrame)
+		var got uint64
+		if v.intel {
+			got = r.IntelSignal(v.start, v.length)
+		} else {
+			got = r.MotorolaSignal(v.start, v.length)
+		}
+		if got != v.expected {
+			t.Fatalf("signal %+v: got %#x", v, got)
+		}
+		expect(t, uint(0), r.At())
+		expect(t, nil, r.Error())
+	}
+	// signals are relative to the reader position
+	r := NewReader(append([]byte{0xFF, 0xFF}, canFrame...))
+	r.Skip(16)
+	expect(t, uint64(0x1234), r.MotorolaSignal(7, 16))
+	expect(t, uint64(0x3412), r.IntelSignal(0, 16))
+	expect(t, nil, r.Error())
+	r = NewReader(canFrame[:2])
+	r.IntelSignal(12, 8)
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestPutCANSignals(t *testing.T) {
+	frame := make([]byte, len(canFrame))
+	for i := 0; i < 2; i++ {
+		for _, v := range canSignals {
+			w := NewWriter(frame)
+			if v.intel {
+				w.PutIntelSignal(v.start, v.length, v.expected)
+			} else {
+				w.PutMotorolaSignal(v.start, v.length, v.expected)
+			}
+			expect(t, 0, w.Index())
+			expect(t, nil, w.Flush())
+		}
+		compare(t, canFrame, frame)
+		// overwriting keeps other bits
+		for j := range frame {
+			frame[j] = ^canFrame[j]
+		}
+		w := NewWriter(frame)
+		w.PutMotorolaSignal(7, 64, 0x123456789ABCDEF0)
+		compare(t, canFrame, frame)
+	}
+	buf := []byte{0xAA, 0x00, 0x00, 0x00}
+	w := NewWriter(buf)
+	w.PutByte(0x55)
+	w.PutIntelSignal(4, 12, 0xABC)
+	w.PutMotorolaSignal(23, 4, 0x9)
+	expect(t, []byte{0x55, 0xC0, 0xAB, 0x90}, buf)
+	expect(t, 8, w.Index())
+	w.PutIntelSignal(20, 8, 0)
+	expect(t, ErrOverflow, w.Flush())
+}
@@ -0,0 +1,78 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// BitCRC computes bit-level CRCs over individual fields, as used by
+// field-protected industrial buses.
+// CRCs are computed most significant bit first, without reflection nor
+// final xor.
+type BitCRC struct {
+	poly  uint64
+	init  uint64
+	width uint
+}
+
+// NewBitCRC returns a new CRC of <width> bits, up to 64, with polynomial
+// <poly> in normal notation & initial value <init>.
+func NewBitCRC(poly uint64, width uint, init uint64) BitCRC {
+	return BitCRC{
+		poly:  poly,
+		init:  init,
+		width: width,
+	}
+}
+
+// Checksum returns the CRC of the <bits> low bits of <val>.
+func (c BitCRC) Checksum(val uint64, bits uint) uint64 {
+	top := uint64(1) << (c.width - 1)
+	mask := top<<1 - 1
+	crc := c.init & mask
+	for i := bits; i > 0; i-- {
+		b := val >> (i - 1) & 1
+		if (crc&top != 0) != (b != 0) {
+			crc = crc<<1 ^ c.poly
+		} else {
+			crc <<= 1
+		}
+		crc &= mask
+	}
+	return crc
+}
+
+// Read reads a field of up to 64 bits followed by its CRC.
+// Returns whether the CRC matches. The reader advances even on mismatch.
+func (c BitCRC) Read(r *Reader, bits uint) (uint64, bool) {
+	val := r.Uint64(bits)
+	crc := r.Uint64(c.width)
+	return val, crc == c.Checksum(val, bits)
+}
+
+// NewReaderBitCRC returns a new reader reading from <src> which checks
+// field CRCs with polynomial <poly> & initial value <init>, see
+// ReadWithCRC.
+func NewReaderBitCRC(src []byte, poly, init uint64) Reader {
+	r := NewReader(src)
+	r.bitCRC = BitCRC{poly: poly, init: init}
+	return r
+}
+
+// ReadWithCRC reads a field of up to 64 bits followed by its CRC of
+// <crcBits> bits, computed with the polynomial given to NewReaderBitCRC.
+// Returns whether the CRC matches. The reader advances even on mismatch.
+// Readers without a polynomial, like those from NewReader, always report
+// a mismatch.
+func (r *Reader) ReadWithCRC(bits, crcBits uint) (uint64, bool) {
+	c := r.bitCRC
+	c.width = crcBits
+	val, ok := c.Read(r, bits)
+	return val, ok && c.poly != 0
+}
+
+// Put writes a field of up to 64 bits followed by its CRC.
+func (c BitCRC) Put(w *Writer, bits uint, val uint64) {
+	val &= 1<<bits - 1
+	w.PutUint64(bits, val)
+	w.PutUint64(c.width, c.Checksum(val, bits))
+}
@@ -0,0 +1,71 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitCRC(t *testing.T) {
+	crc8 := NewBitCRC(0x07, 8, 0)
+	expect(t, uint64(0x97), crc8.Checksum(0x31, 8))
+	// check value of CRC-16/CCITT-FALSE over "12345678"
+	ccitt := NewBitCRC(0x1021, 16, 0xFFFF)
+	expect(t, uint64(0xA12B), ccitt.Checksum(0x3132333435363738, 64))
+	// CAN uses a 15-bit CRC
+	can := NewBitCRC(0x4599, 15, 0)
+	expect(t, uint64(0x681A), can.Checksum(0x5A3, 11))
+	// 64-bit CRC
+	ecma := NewBitCRC(0x42F0E1EBA9EA3693, 64, 0)
+	expect(t, uint64(0), ecma.Checksum(0, 64))
+}
+
+func TestBitCRCFields(t *testing.T) {
+	can := NewBitCRC(0x4599, 15, 0)
+	buf := make([]byte, 64)
+	w := NewWriter(buf)
+	var values []uint64
+	for i := 0; i < 16; i++ {
+		v := rand.Uint64() & 0x7FF
+		values = append(values, v)
+		can.Put(&w, 11, v)
+	}
+	expect(t, 16*26, w.Index())
+	expect(t, nil, w.Flush())
+	r := NewReader(buf)
+	for _, v := range values {
+		got, ok := can.Read(&r, 11)
+		expect(t, v, got)
+		expect(t, true, ok)
+	}
+	// corrupt one bit
+	buf[0] ^= 0x10
+	r.Reset()
+	_, ok := can.Read(&r, 11)
+	expect(t, false, ok)
+	expect(t, uint(26), r.At())
+	r = NewReaderBitCRC(buf, 0x4599, 0)
+	_, ok = r.ReadWithCRC(11, 15)
+	expect(t, false, ok)
+	expect(t, uint(26), r.At())
+	for _, v := range values[1:] {
+		got, ok := r.ReadWithCRC(11, 15)
+		expect(t, v, got)
+		expect(t, true, ok)
+	}
+	// plain readers have no polynomial to check against
+	r = NewReader(buf)
+	r.Skip(26)
+	for _, v := range values[1:] {
+		got, ok := r.ReadWithCRC(11, 15)
+		expect(t, v, got)
+		expect(t, false, ok)
+	}
+	r = NewReader(make([]byte, 4))
+	_, ok = r.ReadWithCRC(11, 15)
+	expect(t, false, ok)
+	expect(t, uint(26), r.At())
+}
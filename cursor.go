@@ -0,0 +1,73 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"encoding/binary"
+)
+
+// FieldCursor serves many small consecutive reads from a single 64-bit
+// window, loading the next window only when the current one is exhausted.
+// It returns exactly what successive Reader.Uint32 calls would, bit orders,
+// overflow policies, ends & limits included: windows are only served from
+// plain readers & well before their end, other reads go through Uint32.
+// It keeps the wrapped reader position up to date, but the reader must not
+// be moved directly while the cursor is in use.
+type FieldCursor struct {
+	r    *Reader
+	win  uint64
+	left uint
+}
+
+// NewFieldCursor returns a new cursor reading from <r>.
+func NewFieldCursor(r *Reader) FieldCursor {
+	return FieldCursor{r: r}
+}
+
+// refill loads the window at the reader position, or returns false if
+// the reader must serve the next read itself.
+func (c *FieldCursor) refill() bool {
+	r := c.r
+	skip := r.idx >> 3
+	// fast is zero on ordered readers & policies
+	if r.fast == 0 || skip > r.max || skip<<3+64 > r.end {
+		c.left = 0
+		return false
+	}
+	shift := r.idx & 7
+	c.win = binary.BigEndian.Uint64(r.src[skip:]) << shift
+	c.left = 64 - shift
+	return true
+}
+
+// Read reads up to 32 unsigned bits in big-endian order.
+func (c *FieldCursor) Read(bits uint) uint32 {
+	if bits > c.left {
+		return slowCursor(c, bits, (*FieldCursor).readSlow)
+	}
+	return c.take(bits)
+}
+
+// slowCursor keeps Read within the inlining budget, like slowRead
+func slowCursor(c *FieldCursor, bits uint, read func(*FieldCursor, uint) uint32) uint32 {
+	return read(c, bits)
+}
+
+// readSlow is Read for exhausted windows
+func (c *FieldCursor) readSlow(bits uint) uint32 {
+	if !c.refill() {
+		return c.r.Uint32(bits)
+	}
+	return c.take(bits)
+}
+
+// take reads <bits> from the window
+func (c *FieldCursor) take(bits uint) uint32 {
+	val := uint32(c.win >> (64 - bits))
+	c.win <<= bits
+	c.left -= bits
+	c.r.idx += bits
+	return val
+}
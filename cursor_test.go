@@ -0,0 +1,82 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFieldCursor(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 8, 9, 64, 1021} {
+		src := makeSource(size)
+		r := NewReader(src)
+		c := NewFieldCursor(&r)
+		ref := NewReader(src)
+		for ref.At() < uint(size*8)+96 {
+			bits := uint(rand.Intn(33))
+			expect(t, ref.Uint32(bits), c.Read(bits))
+			expect(t, ref.At(), r.At())
+		}
+		expect(t, ErrOverflow, r.Error())
+	}
+	src := makeSource(64)
+	for _, open := range []func() Reader{
+		func() Reader { return NewReaderLSB(src) },
+		func() Reader { return NewReaderPolicy(src, OverflowSaturate) },
+		func() Reader { return NewReaderLimit(src, 301) },
+	} {
+		r, ref := open(), open()
+		c := NewFieldCursor(&r)
+		for ref.At() < 600 && ref.Error() == nil {
+			bits := uint(rand.Intn(33))
+			expect(t, ref.Uint32(bits), c.Read(bits))
+			expect(t, ref.At(), r.At())
+		}
+		expect(t, ErrOverflow, r.Error())
+	}
+	// limits are honored too
+	r := NewReader(src)
+	ref := NewReader(src)
+	c := NewFieldCursor(&r)
+	release, refRelease := r.Limit(77), ref.Limit(77)
+	for ref.At() < 120 {
+		bits := uint(rand.Intn(33))
+		expect(t, ref.Uint32(bits), c.Read(bits))
+		expect(t, ref.At(), r.At())
+	}
+	release()
+	refRelease()
+	expect(t, ErrOverflow, r.Error())
+}
+
+var fieldWidths = []uint{1, 3, 2, 5, 1, 1, 4, 7}
+
+func BenchmarkSmallFields(b *testing.B) {
+	buf := makeSource(1024)
+	b.Run("uint32", func(b *testing.B) {
+		b.SetBytes(int64(len(buf)))
+		for i := 0; i < b.N; i++ {
+			r := NewReader(buf)
+			for r.LeftBits() >= 24 {
+				for _, bits := range fieldWidths {
+					Output += int64(r.Uint32(bits))
+				}
+			}
+		}
+	})
+	b.Run("cursor", func(b *testing.B) {
+		b.SetBytes(int64(len(buf)))
+		for i := 0; i < b.N; i++ {
+			r := NewReader(buf)
+			c := NewFieldCursor(&r)
+			for r.LeftBits() >= 24 {
+				for _, bits := range fieldWidths {
+					Output += int64(c.Read(bits))
+				}
+			}
+		}
+	})
+}
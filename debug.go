@@ -0,0 +1,298 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build iobitdebug
+
+package iobit
+
+import (
+	"fmt"
+)
+
+// Op is one traced reader operation.
+type Op struct {
+	Name string // method name
+	Bits uint   // number of bits read or skipped
+	At   uint   // reader position after the operation
+}
+
+func (o Op) String() string {
+	return fmt.Sprintf("%v(%v) -> %v", o.Name, o.Bits, o.At)
+}
+
+// DebugReader wraps a reader and records every operation so that the
+// sequence which led to a parse error can be dumped.
+// It covers fixed-size reads, Align, Seek & the exp-golomb, golomb &
+// varint decoders. Other helpers must go through Reader & are not traced.
+// It is much slower than a plain reader and only meant for diagnostics,
+// so it is only built with the iobitdebug tag.
+type DebugReader struct {
+	r     *Reader
+	trace []Op
+}
+
+// NewDebugReader returns a new debug reader tracing reads from <r>.
+func NewDebugReader(r *Reader) *DebugReader {
+	return &DebugReader{r: r}
+}
+
+func (d *DebugReader) record(name string, bits uint) {
+	d.trace = append(d.trace, Op{name, bits, d.r.At()})
+}
+
+// recordFrom records an operation of variable size started at <start>.
+func (d *DebugReader) recordFrom(name string, start uint) {
+	d.record(name, d.r.At()-start)
+}
+
+// Trace returns all operations recorded so far.
+func (d *DebugReader) Trace() []Op {
+	return d.trace
+}
+
+// Reader returns the wrapped reader.
+func (d *DebugReader) Reader() *Reader {
+	return d.r
+}
+
+// Bit reads the next bit as a boolean.
+func (d *DebugReader) Bit() bool {
+	v := d.r.Bit()
+	d.record("Bit", 1)
+	return v
+}
+
+// Byte reads one byte.
+func (d *DebugReader) Byte() uint8 {
+	v := d.r.Byte()
+	d.record("Byte", 8)
+	return v
+}
+
+// Uint8 reads up to 8 unsigned bits in big-endian order.
+func (d *DebugReader) Uint8(bits uint) uint8 {
+	v := d.r.Uint8(bits)
+	d.record("Uint8", bits)
+	return v
+}
+
+// Int8 reads up to 8 signed bits in big-endian order.
+func (d *DebugReader) Int8(bits uint) int8 {
+	v := d.r.Int8(bits)
+	d.record("Int8", bits)
+	return v
+}
+
+// Uint16 reads up to 16 unsigned bits in big-endian order.
+func (d *DebugReader) Uint16(bits uint) uint16 {
+	v := d.r.Uint16(bits)
+	d.record("Uint16", bits)
+	return v
+}
+
+// Int16 reads up to 16 signed bits in big-endian order.
+func (d *DebugReader) Int16(bits uint) int16 {
+	v := d.r.Int16(bits)
+	d.record("Int16", bits)
+	return v
+}
+
+// Be16 reads 16 unsigned bits in big-endian order.
+func (d *DebugReader) Be16() uint16 {
+	v := d.r.Be16()
+	d.record("Be16", 16)
+	return v
+}
+
+// Le16 reads 16 unsigned bits in little-endian order.
+func (d *DebugReader) Le16() uint16 {
+	v := d.r.Le16()
+	d.record("Le16", 16)
+	return v
+}
+
+// Uint32 reads up to 32 unsigned bits in big-endian order.
+func (d *DebugReader) Uint32(bits uint) uint32 {
+	v := d.r.Uint32(bits)
+	d.record("Uint32", bits)
+	return v
+}
+
+// Int32 reads up to 32 signed bits in big-endian order.
+func (d *DebugReader) Int32(bits uint) int32 {
+	v := d.r.Int32(bits)
+	d.record("Int32", bits)
+	return v
+}
+
+// Be32 reads 32 unsigned bits in big-endian order.
+func (d *DebugReader) Be32() uint32 {
+	v := d.r.Be32()
+	d.record("Be32", 32)
+	return v
+}
+
+// Le32 reads 32 unsigned bits in little-endian order.
+func (d *DebugReader) Le32() uint32 {
+	v := d.r.Le32()
+	d.record("Le32", 32)
+	return v
+}
+
+// Uint64 reads up to 64 unsigned bits in big-endian order.
+func (d *DebugReader) Uint64(bits uint) uint64 {
+	v := d.r.Uint64(bits)
+	d.record("Uint64", bits)
+	return v
+}
+
+// Int64 reads up to 64 signed bits in big-endian order.
+func (d *DebugReader) Int64(bits uint) int64 {
+	v := d.r.Int64(bits)
+	d.record("Int64", bits)
+	return v
+}
+
+// Be64 reads 64 unsigned bits in big-endian order.
+func (d *DebugReader) Be64() uint64 {
+	v := d.r.Be64()
+	d.record("Be64", 64)
+	return v
+}
+
+// Le64 reads 64 unsigned bits in little-endian order.
+func (d *DebugReader) Le64() uint64 {
+	v := d.r.Le64()
+	d.record("Le64", 64)
+	return v
+}
+
+// Bytes returns a byte-array of input size.
+func (d *DebugReader) Bytes(size int) []byte {
+	v := d.r.Bytes(size)
+	d.record("Bytes", uint(len(v)*8))
+	return v
+}
+
+// Skip skips n bits.
+func (d *DebugReader) Skip(bits uint) {
+	d.r.Skip(bits)
+	d.record("Skip", bits)
+}
+
+// Align skips up to the next byte boundary and returns the number of
+// skipped bits.
+func (d *DebugReader) Align() uint {
+	n := d.r.Align()
+	d.record("Align", n)
+	return n
+}
+
+// Seek moves the reader to absolute position <bit>, recorded as an
+// operation of zero bits.
+func (d *DebugReader) Seek(bit uint) {
+	d.r.Seek(bit)
+	d.record("Seek", 0)
+}
+
+// Ue32 reads an unsigned exp-golomb code.
+func (d *DebugReader) Ue32() uint32 {
+	start := d.r.At()
+	v := d.r.Ue32()
+	d.recordFrom("Ue32", start)
+	return v
+}
+
+// Ue64 reads an unsigned exp-golomb code of up to 63 leading zeros.
+func (d *DebugReader) Ue64() uint64 {
+	start := d.r.At()
+	v := d.r.Ue64()
+	d.recordFrom("Ue64", start)
+	return v
+}
+
+// Se32 reads a signed exp-golomb code.
+func (d *DebugReader) Se32() int32 {
+	start := d.r.At()
+	v := d.r.Se32()
+	d.recordFrom("Se32", start)
+	return v
+}
+
+// Se64 reads a signed exp-golomb code of up to 63 leading zeros.
+func (d *DebugReader) Se64() int64 {
+	start := d.r.At()
+	v := d.r.Se64()
+	d.recordFrom("Se64", start)
+	return v
+}
+
+// InterleavedExpGolomb reads an interleaved exp-golomb code.
+func (d *DebugReader) InterleavedExpGolomb() uint32 {
+	start := d.r.At()
+	v := d.r.InterleavedExpGolomb()
+	d.recordFrom("InterleavedExpGolomb", start)
+	return v
+}
+
+// Unary reads a unary code.
+func (d *DebugReader) Unary() uint {
+	start := d.r.At()
+	v := d.r.Unary()
+	d.recordFrom("Unary", start)
+	return v
+}
+
+// Rice reads a Golomb-Rice code of parameter <k>.
+func (d *DebugReader) Rice(k uint) uint32 {
+	start := d.r.At()
+	v := d.r.Rice(k)
+	d.recordFrom("Rice", start)
+	return v
+}
+
+// Golomb reads a Golomb code of divisor <m>.
+func (d *DebugReader) Golomb(m uint32) uint32 {
+	start := d.r.At()
+	v := d.r.Golomb(m)
+	d.recordFrom("Golomb", start)
+	return v
+}
+
+// VarintBE reads a big-endian varint.
+func (d *DebugReader) VarintBE() uint64 {
+	start := d.r.At()
+	v := d.r.VarintBE()
+	d.recordFrom("VarintBE", start)
+	return v
+}
+
+// MidiVLQ reads a MIDI variable-length quantity.
+func (d *DebugReader) MidiVLQ() uint32 {
+	start := d.r.At()
+	v := d.r.MidiVLQ()
+	d.recordFrom("MidiVLQ", start)
+	return v
+}
+
+// Uleb128 reads an unsigned LEB128 varint.
+func (d *DebugReader) Uleb128() uint64 {
+	start := d.r.At()
+	v := d.r.Uleb128()
+	d.recordFrom("Uleb128", start)
+	return v
+}
+
+// Sleb128 reads a signed LEB128 varint.
+func (d *DebugReader) Sleb128() int64 {
+	start := d.r.At()
+	v := d.r.Sleb128()
+	d.recordFrom("Sleb128", start)
+	return v
+}
+
+// Error returns whether the reader encountered an error.
+func (d *DebugReader) Error() error {
+	return d.r.Error()
+}
@@ -0,0 +1,69 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build iobitdebug
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestDebugReader(t *testing.T) {
+	buf := []byte{0x47, 0x40, 0x11, 0x10, 0xAB}
+	r := NewReader(buf)
+	d := NewDebugReader(&r)
+	expect(t, uint32(0x47), d.Uint32(8))
+	expect(t, false, d.Bit())
+	expect(t, int32(-2), d.Int32(2))
+	d.Skip(5)
+	expect(t, uint64(0x11), d.Uint64(8))
+	expect(t, int64(0x10), d.Int64(8))
+	expect(t, []byte{0xAB}, d.Bytes(4))
+	d.Skip(1)
+	expect(t, ErrOverflow, d.Error())
+	expect(t, []Op{
+		{"Uint32", 8, 8},
+		{"Bit", 1, 9},
+		{"Int32", 2, 11},
+		{"Skip", 5, 16},
+		{"Uint64", 8, 24},
+		{"Int64", 8, 32},
+		{"Bytes", 8, 40},
+		{"Skip", 1, 41},
+	}, d.Trace())
+	expect(t, "Skip(1) -> 41", d.Trace()[7].String())
+	expect(t, &r, d.Reader())
+
+	buf = make([]byte, 9)
+	w := NewWriter(buf)
+	w.PutByte(0x12)
+	w.PutLe16(0x1234)
+	w.PutUint32(5, 0x7)
+	w.PutUint32(3, 0)
+	w.PutVarintBE(300)
+	w.PutUleb128(624485)
+	expect(t, nil, w.Flush())
+	r = NewReader(buf)
+	d = NewDebugReader(&r)
+	expect(t, uint8(0x12), d.Byte())
+	expect(t, uint16(0x1234), d.Le16())
+	expect(t, uint32(6), d.Ue32())
+	expect(t, uint(3), d.Align())
+	expect(t, uint64(300), d.VarintBE())
+	expect(t, uint64(624485), d.Uleb128())
+	d.Seek(8)
+	expect(t, uint16(0x3412), d.Be16())
+	expect(t, nil, d.Error())
+	expect(t, []Op{
+		{"Byte", 8, 8},
+		{"Le16", 16, 24},
+		{"Ue32", 5, 29},
+		{"Align", 3, 32},
+		{"VarintBE", 16, 48},
+		{"Uleb128", 24, 72},
+		{"Seek", 0, 8},
+		{"Be16", 16, 24},
+	}, d.Trace())
+}
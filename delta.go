@@ -0,0 +1,44 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// DeltaReader decodes values stored as signed deltas from the previous
+// value.
+type DeltaReader struct {
+	r    *Reader
+	prev int64
+}
+
+// NewDeltaReader returns a new delta reader reading from <r> and starting
+// from <prev>.
+func NewDeltaReader(r *Reader, prev int64) DeltaReader {
+	return DeltaReader{r: r, prev: prev}
+}
+
+// Decode reads a signed delta of up to 64 bits and returns the
+// reconstructed value.
+func (d *DeltaReader) Decode(bits uint) int64 {
+	d.prev += d.r.Int64(bits)
+	return d.prev
+}
+
+// DeltaWriter encodes values as signed deltas from the previous value.
+type DeltaWriter struct {
+	w    *Writer
+	prev int64
+}
+
+// NewDeltaWriter returns a new delta writer writing to <w> and starting
+// from <prev>.
+func NewDeltaWriter(w *Writer, prev int64) DeltaWriter {
+	return DeltaWriter{w: w, prev: prev}
+}
+
+// Encode writes the delta between <val> and the previous value on up to
+// 64 signed bits.
+func (d *DeltaWriter) Encode(bits uint, val int64) {
+	d.w.PutInt64(bits, val-d.prev)
+	d.prev = val
+}
@@ -0,0 +1,34 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	// ramp up then down
+	var values []int64
+	for i := int64(0); i < 64; i++ {
+		values = append(values, 1000+i*3)
+	}
+	for i := int64(0); i < 64; i++ {
+		values = append(values, 1189-i*5)
+	}
+	buf := make([]byte, len(values))
+	w := NewWriter(buf)
+	dw := NewDeltaWriter(&w, 1000)
+	for _, v := range values {
+		dw.Encode(5, v)
+	}
+	expect(t, nil, w.Flush())
+	r := NewReader(buf)
+	dr := NewDeltaReader(&r, 1000)
+	for _, v := range values {
+		expect(t, v, dr.Decode(5))
+	}
+	expect(t, nil, r.Error())
+	expect(t, 5*len(values), int(r.At()))
+}
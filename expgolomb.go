@@ -0,0 +1,131 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+	"math/bits"
+)
+
+// InterleavedExpGolomb reads an interleaved exp-golomb code as used by
+// Dirac & VC-2, where each data bit follows a zero continuation bit and a
+// final one bit ends the code.
+// Flags ErrTooLong if the code holds more than 32 bits or exceeds the
+// maximum code length, & ErrTruncated if it runs past the reader end.
+func (r *Reader) InterleavedExpGolomb() uint32 {
+	val := uint64(1)
+	start := r.idx
+	for !r.Bit() {
+		if val > 0xFFFFFFFF {
+			r.err = ErrTooLong
+			return 0
+		}
+		val = val<<1 | uint64(r.Uint32(1))
+		// the final one bit is still ahead
+		if r.codeTooLong(start-1) || r.codeTruncated() {
+			return 0
+		}
+	}
+	if r.codeTruncated() {
+		return 0
+	}
+	if val-1 > math.MaxUint32 {
+		r.err = ErrTooLong
+		return 0
+	}
+	return uint32(val - 1)
+}
+
+// ue reads an exp-golomb code with at most <zeros> leading zeros.
+func (r *Reader) ue(zeros uint) uint64 {
+	start := r.idx
+	n := uint(0)
+	for !r.Bit() {
+		if n == zeros {
+			r.err = ErrTooLong
+			return 0
+		}
+		n++
+		if r.codeTooLong(start) || r.codeTruncated() {
+			return 0
+		}
+	}
+	suffix := r.Uint64(n)
+	if r.codeTooLong(start) || r.codeTruncated() {
+		return 0
+	}
+	return 1<<n - 1 + suffix
+}
+
+// Ue32 reads an unsigned exp-golomb code, ue(v) in H.264 & H.265, made of
+// n zero bits, a one bit & n suffix bits.
+// Flags ErrTooLong past 31 leading zeros or the maximum code length, &
+// ErrTruncated if the code runs past the reader end.
+func (r *Reader) Ue32() uint32 {
+	return uint32(r.ue(31))
+}
+
+// Ue64 reads an unsigned exp-golomb code like Ue32 but with up to 63
+// leading zeros.
+func (r *Reader) Ue64() uint64 {
+	return r.ue(63)
+}
+
+// se maps an exp-golomb code number to 0, 1, -1, 2, -2...
+// The magnitude is computed before the sign so that no value overflows.
+func se(k uint64) int64 {
+	if k&1 != 0 {
+		return int64(k>>1 + 1)
+	}
+	return -int64(k >> 1)
+}
+
+// Se32 reads a signed exp-golomb code, se(v) in H.264 & H.265, mapping
+// Ue32 code numbers to 0, 1, -1, 2, -2...
+func (r *Reader) Se32() int32 {
+	return int32(se(r.ue(31)))
+}
+
+// Se64 reads a signed exp-golomb code like Se32 but with up to 63 leading
+// zeros.
+func (r *Reader) Se64() int64 {
+	return se(r.ue(63))
+}
+
+// PutInterleavedExpGolomb writes an interleaved exp-golomb code as used
+// by Dirac & VC-2.
+func (w *Writer) PutInterleavedExpGolomb(val uint32) {
+	v := uint64(val) + 1
+	n := uint(63)
+	for v>>n == 0 {
+		n--
+	}
+	for n > 0 {
+		n--
+		w.PutUint32(2, uint32(v>>n&1))
+	}
+	w.PutBit(true)
+}
+
+// putUe writes <v> - 1 as an exp-golomb code, <v> being at least one.
+func (w *Writer) putUe(v uint64) {
+	n := uint(bits.Len64(v))
+	w.PutUint64(n-1, 0)
+	w.PutUint64(n, v)
+}
+
+// PutUe32 writes an unsigned exp-golomb code, ue(v) in H.264 & H.265.
+func (w *Writer) PutUe32(val uint32) {
+	w.putUe(uint64(val) + 1)
+}
+
+// PutSe32 writes a signed exp-golomb code, se(v) in H.264 & H.265.
+func (w *Writer) PutSe32(val int32) {
+	k := uint64(-2 * int64(val))
+	if val > 0 {
+		k = uint64(2*int64(val) - 1)
+	}
+	w.putUe(k + 1)
+}
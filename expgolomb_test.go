@@ -0,0 +1,189 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// refCode returns a bit string as a left-aligned value & its size
+func refCode(bits string) (uint64, uint) {
+	var val uint64
+	for _, c := range bits {
+		val <<= 1
+		if c == '1' {
+			val |= 1
+		}
+	}
+	return val, uint(len(bits))
+}
+
+func TestInterleavedExpGolomb(t *testing.T) {
+	// from the VC-2 specification, section 5.5.3
+	for i, code := range []string{
+		"1", "001", "011", "00001", "00011", "01001", "01011", "0000001",
+	} {
+		v, n := refCode(code)
+		buf := make([]byte, 1)
+		w := NewWriter(buf)
+		w.PutInterleavedExpGolomb(uint32(i))
+		expect(t, int(n), w.Index())
+		w.PutUint32(8-n, 0)
+		expect(t, nil, w.Flush())
+		expect(t, v<<(8-n), uint64(buf[0]))
+		r := NewReader(buf)
+		expect(t, uint32(i), r.InterleavedExpGolomb())
+		expect(t, n, r.At())
+	}
+	values := []uint32{0, 1, math.MaxUint32, math.MaxUint32 - 1}
+	for i := 0; i < 256; i++ {
+		values = append(values, rand.Uint32()>>rand.Intn(32))
+	}
+	buf := make([]byte, len(values)*9)
+	w := NewWriter(buf)
+	for _, v := range values {
+		w.PutInterleavedExpGolomb(v)
+	}
+	w.PutUint32(uint(-w.Index()&7), 0)
+	expect(t, nil, w.Flush())
+	r := NewReader(buf)
+	for _, v := range values {
+		expect(t, v, r.InterleavedExpGolomb())
+	}
+	expect(t, nil, r.Error())
+	// runs of zeros never end
+	r = NewReader(make([]byte, 16))
+	expect(t, uint32(0), r.InterleavedExpGolomb())
+	expect(t, ErrTooLong, r.Error())
+	// 32 data bits all set overflow by one
+	r = NewReader([]byte{0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x55, 0x80})
+	expect(t, uint32(0), r.InterleavedExpGolomb())
+	expect(t, ErrTooLong, r.Error())
+}
+
+func TestUe(t *testing.T) {
+	// from the H.264 specification, table 9-2
+	for i, code := range []string{
+		"1", "010", "011", "00100", "00101", "00110", "00111", "0001000",
+		"0001001", "0001010",
+	} {
+		v, n := refCode(code)
+		buf := make([]byte, 2)
+		buf[0] = byte(v << (16 - n) >> 8)
+		buf[1] = byte(v << (16 - n))
+		r := NewReader(buf)
+		expect(t, uint32(i), r.Ue32())
+		expect(t, n, r.At())
+		r = NewReader(buf)
+		expect(t, uint64(i), r.Ue64())
+		expect(t, n, r.At())
+		expect(t, nil, r.Error())
+	}
+	// largest codes
+	buf := make([]byte, 32)
+	buf[3] = 0x01
+	for i := 4; i < 8; i++ {
+		buf[i] = 0xFF
+	}
+	buf[7] = 0xFE
+	r := NewReader(buf)
+	expect(t, uint32(math.MaxUint32-1), r.Ue32())
+	expect(t, uint(63), r.At())
+	buf = make([]byte, 32)
+	buf[7] = 0x01
+	for i := 8; i < 16; i++ {
+		buf[i] = 0xFF
+	}
+	buf[15] = 0xFE
+	r = NewReader(buf)
+	expect(t, uint64(math.MaxUint64-1), r.Ue64())
+	expect(t, uint(127), r.At())
+	expect(t, nil, r.Error())
+	// too many leading zeros
+	r = NewReader(make([]byte, 16))
+	expect(t, uint32(0), r.Ue32())
+	expect(t, ErrTooLong, r.Error())
+	r = NewReader(make([]byte, 16))
+	expect(t, uint64(0), r.Ue64())
+	expect(t, ErrTooLong, r.Error())
+	// truncated codes
+	r = NewReader([]byte{0x00, 0x40})
+	expect(t, uint32(0), r.Ue32())
+	expect(t, ErrTruncated, r.Error())
+}
+
+func TestSe(t *testing.T) {
+	// from the H.264 specification, table 9-3
+	for i, v := range []int64{0, 1, -1, 2, -2, 3, -3, 4, -4} {
+		buf := make([]byte, 8)
+		w := NewWriter(buf)
+		w.PutUint64(uint(bits.Len64(uint64(i)+1)-1), 0)
+		w.PutUint64(uint(bits.Len64(uint64(i)+1)), uint64(i)+1)
+		n := uint(w.Index())
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		expect(t, int32(v), r.Se32())
+		r = NewReader(buf)
+		expect(t, v, r.Se64())
+		expect(t, n, r.At())
+	}
+	expect(t, int64(math.MaxInt32), se(math.MaxUint32-2))
+	expect(t, int64(math.MinInt32+1), se(math.MaxUint32-1))
+	expect(t, int64(math.MaxInt64), se(math.MaxUint64-2))
+	expect(t, int64(math.MinInt64+1), se(math.MaxUint64-1))
+	// largest code numbers
+	buf := make([]byte, 32)
+	buf[3] = 0x01
+	for i := 4; i < 8; i++ {
+		buf[i] = 0xFF
+	}
+	buf[7] = 0xFE
+	r := NewReader(buf)
+	expect(t, int32(math.MinInt32+1), r.Se32())
+	buf[7] = 0xFC
+	r = NewReader(buf)
+	expect(t, int32(math.MaxInt32), r.Se32())
+	expect(t, nil, r.Error())
+}
+
+func TestPutUe(t *testing.T) {
+	buf := make([]byte, 1)
+	w := NewWriter(buf)
+	w.PutUe32(0)
+	expect(t, 1, w.Index())
+	w.PutUe32(3)
+	expect(t, 6, w.Index())
+	w.PutUint32(2, 0)
+	expect(t, nil, w.Flush())
+	expect(t, []byte{0x90}, buf)
+	unsigned := []uint32{0, 1, 2, 7, 8, math.MaxUint32 - 1, math.MaxUint32}
+	signed := []int32{0, 1, -1, 2, -2, math.MaxInt32, math.MinInt32 + 1, math.MinInt32}
+	for i := 0; i < 256; i++ {
+		unsigned = append(unsigned, rand.Uint32()>>rand.Intn(32))
+		signed = append(signed, int32(rand.Uint32())>>rand.Intn(32))
+	}
+	buf = make([]byte, (len(unsigned)+len(signed))*9)
+	w = NewWriter(buf)
+	for _, v := range unsigned {
+		w.PutUe32(v)
+	}
+	for _, v := range signed {
+		w.PutSe32(v)
+	}
+	w.PutUint32(uint(-w.Index()&7), 0)
+	expect(t, nil, w.Flush())
+	r := NewReader(buf)
+	for _, v := range unsigned {
+		expect(t, uint64(v), r.Ue64())
+	}
+	for _, v := range signed {
+		expect(t, int64(v), r.Se64())
+	}
+	expect(t, nil, r.Error())
+}
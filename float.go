@@ -0,0 +1,115 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+)
+
+// Float32 reads a 32-bit IEEE-754 float in big-endian order.
+func (r *Reader) Float32() float32 {
+	return math.Float32frombits(r.Uint32(32))
+}
+
+// Float64 reads a 64-bit IEEE-754 float in big-endian order.
+func (r *Reader) Float64() float64 {
+	return math.Float64frombits(r.Uint64(64))
+}
+
+// Float32Le reads a 32-bit IEEE-754 float in little-endian order.
+func (r *Reader) Float32Le() float32 {
+	return math.Float32frombits(r.Le32())
+}
+
+// Float64Le reads a 64-bit IEEE-754 float in little-endian order.
+func (r *Reader) Float64Le() float64 {
+	return math.Float64frombits(r.Le64())
+}
+
+// PutFloat32 writes a 32-bit IEEE-754 float in big-endian order.
+func (w *Writer) PutFloat32(val float32) {
+	w.PutUint32(32, math.Float32bits(val))
+}
+
+// PutFloat64 writes a 64-bit IEEE-754 float in big-endian order.
+func (w *Writer) PutFloat64(val float64) {
+	w.PutUint64(64, math.Float64bits(val))
+}
+
+// PutFloat32Le writes a 32-bit IEEE-754 float in little-endian order.
+func (w *Writer) PutFloat32Le(val float32) {
+	w.PutLe32(math.Float32bits(val))
+}
+
+// PutFloat64Le writes a 64-bit IEEE-754 float in little-endian order.
+func (w *Writer) PutFloat64Le(val float64) {
+	w.PutLe64(math.Float64bits(val))
+}
+
+// Float16 reads a 16-bit IEEE-754 half-precision float in big-endian order.
+func (r *Reader) Float16() float32 {
+	h := uint32(r.Uint16(16))
+	sign := h >> 15 << 31
+	exp := h >> 10 & 0x1F
+	mant := h & 0x3FF
+	switch {
+	case exp == 0x1F:
+		exp = 0xFF
+	case exp != 0:
+		exp += 127 - 15
+	case mant != 0:
+		// normalize subnormals
+		exp = 127 - 14
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		mant &= 0x3FF
+	}
+	return math.Float32frombits(sign | exp<<23 | mant<<13)
+}
+
+// PutFloat16 writes <val> as a 16-bit IEEE-754 half-precision float in
+// big-endian order, rounding to nearest even.
+// Values too large saturate to infinity.
+func (w *Writer) PutFloat16(val float32) {
+	w.PutUint16(16, float16(val))
+}
+
+// float16 rounds <val> to the nearest even half-precision float.
+func float16(val float32) uint16 {
+	x := math.Float32bits(val)
+	sign := uint16(x >> 16 & 0x8000)
+	exp := int(x >> 23 & 0xFF)
+	mant := x & 0x7FFFFF
+	if exp == 0xFF {
+		if mant != 0 {
+			// keep nan payload high bits & make sure it stays a nan
+			return sign | 0x7E00 | uint16(mant>>13)
+		}
+		return sign | 0x7C00
+	}
+	e := exp - 127 + 15
+	if e >= 0x1F {
+		return sign | 0x7C00
+	}
+	shift := uint(13)
+	if e <= 0 {
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift = uint(14 - e)
+		e = 0
+	}
+	h := uint32(e)<<10 | mant>>shift
+	rem := mant & (1<<shift - 1)
+	half := uint32(1) << (shift - 1)
+	if rem > half || rem == half && h&1 != 0 {
+		// may carry into the exponent, up to infinity
+		h++
+	}
+	return sign | uint16(h)
+}
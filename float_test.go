@@ -0,0 +1,112 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloats(t *testing.T) {
+	bits32 := []uint32{
+		0x00000000, 0x80000000, 0x3F800000, 0xC0490FDB, 0x00000001,
+		0x7F800000, 0xFF800000, 0x7FC00000, 0x7F800001, 0xFFFFFFFF,
+	}
+	bits64 := []uint64{
+		0x0000000000000000, 0x8000000000000000, 0x3FF0000000000000,
+		0x400921FB54442D18, 0x0000000000000001, 0x7FF0000000000000,
+		0xFFF0000000000000, 0x7FF8000000000000, 0x7FF0000000000001,
+	}
+	for _, skip := range []uint{0, 3} {
+		buf := make([]byte, 1+(len(bits32)*4+len(bits64)*8)*2)
+		w := NewWriter(buf)
+		w.PutUint32(skip, 0)
+		for _, v := range bits32 {
+			w.PutFloat32(math.Float32frombits(v))
+			w.PutFloat32Le(math.Float32frombits(v))
+		}
+		for _, v := range bits64 {
+			w.PutFloat64(math.Float64frombits(v))
+			w.PutFloat64Le(math.Float64frombits(v))
+		}
+		w.PutUint32(8-skip, 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		r.Skip(skip)
+		for _, v := range bits32 {
+			expect(t, v, r.Peek().Uint32(32))
+			expect(t, v, math.Float32bits(r.Float32()))
+			expect(t, v, math.Float32bits(r.Float32Le()))
+		}
+		for _, v := range bits64 {
+			expect(t, v, r.Peek().Uint64(64))
+			expect(t, v, math.Float64bits(r.Float64()))
+			expect(t, v, math.Float64bits(r.Float64Le()))
+		}
+		expect(t, nil, r.Error())
+	}
+	r := NewReader([]byte{0x00, 0x00, 0x80, 0x3F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xF0, 0x3F})
+	expect(t, float32(1), r.Float32Le())
+	expect(t, float64(1), r.Float64Le())
+}
+
+func TestFloat16(t *testing.T) {
+	for _, v := range []struct {
+		half  uint16
+		value float32
+	}{
+		{0x0000, 0},
+		{0x3C00, 1},
+		{0xC000, -2},
+		{0x7BFF, 65504},
+		{0x0001, 1.0 / (1 << 24)},
+		{0x03FF, 1023.0 / (1 << 24)},
+		{0x0400, 1.0 / (1 << 14)},
+		{0x3555, 0.333251953125},
+		{0x7C00, float32(math.Inf(1))},
+		{0xFC00, float32(math.Inf(-1))},
+	} {
+		r := NewReader([]byte{byte(v.half >> 8), byte(v.half)})
+		expect(t, v.value, r.Float16())
+		expect(t, v.half, float16(v.value))
+	}
+	expect(t, uint16(0x8000), float16(float32(math.Copysign(0, -1))))
+	// every half value round-trips
+	buf := make([]byte, 2)
+	for i := 0; i < 1<<16; i++ {
+		buf[0], buf[1] = byte(i>>8), byte(i)
+		r := NewReader(buf)
+		v := r.Float16()
+		w := NewWriter(buf)
+		w.PutFloat16(v)
+		expect(t, nil, w.Flush())
+		h := uint16(buf[0])<<8 | uint16(buf[1])
+		if i&0x7C00 == 0x7C00 && i&0x3FF != 0 {
+			expect(t, true, math.IsNaN(float64(v)))
+			expect(t, uint16(i|0x200), h)
+			continue
+		}
+		expect(t, uint16(i), h)
+	}
+	// rounding
+	for _, v := range []struct {
+		value float32
+		half  uint16
+	}{
+		{1 + 1.0/(1<<11), 0x3C00},
+		{1 + 3.0/(1<<11), 0x3C02},
+		{1 + 1.0/(1<<11) + 1.0/(1<<20), 0x3C01},
+		{65519, 0x7BFF},
+		{65520, 0x7C00},
+		{-1e6, 0xFC00},
+		{1.0 / (1 << 25), 0x0000},
+		{1.5 / (1 << 25), 0x0001},
+		{3.0 / (1 << 25), 0x0002},
+		{1.0 / (1 << 26), 0x0000},
+		{2047.0 / (1 << 25), 0x0400},
+	} {
+		expect(t, v.half, float16(v.value))
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrWidth is the panic value of generic reads & writes of more bits than
+// their type holds, & of ParseTLV entries without any bits.
+var ErrWidth = errors.New("bits exceed type width")
+
+// Unsigned matches every unsigned integer type.
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Signed matches every signed integer type.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// checkWidth panics with ErrWidth if <bits> do not fit in <T>
+func checkWidth[T Unsigned | Signed](bits uint) {
+	var zero T
+	if bits > uint(unsafe.Sizeof(zero))*8 {
+		panic(ErrWidth)
+	}
+}
+
+// ReadUint reads up to 64 unsigned bits in big-endian order into <T>.
+// Panics with ErrWidth if <bits> exceed the width of <T>.
+func ReadUint[T Unsigned](r *Reader, bits uint) T {
+	checkWidth[T](bits)
+	return T(r.Uint64(bits))
+}
+
+// ReadInt reads up to 64 signed bits in big-endian order into <T>.
+// Panics with ErrWidth if <bits> exceed the width of <T>.
+func ReadInt[T Signed](r *Reader, bits uint) T {
+	checkWidth[T](bits)
+	return T(r.Int64(bits))
+}
+
+// PutUint writes the low <bits> of <val>, up to 64, in big-endian order.
+// Panics with ErrWidth if <bits> exceed the width of <T>.
+func PutUint[T Unsigned](w *Writer, bits uint, val T) {
+	checkWidth[T](bits)
+	w.PutUint64(bits, uint64(val))
+}
+
+// PutInt writes the low <bits> of <val>, up to 64, in big-endian order.
+// Panics with ErrWidth if <bits> exceed the width of <T>.
+func PutInt[T Signed](w *Writer, bits uint, val T) {
+	checkWidth[T](bits)
+	w.PutInt64(bits, int64(val))
+}
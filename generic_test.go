@@ -0,0 +1,54 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+type tag uint16
+
+func TestReadGeneric(t *testing.T) {
+	src := []byte{0xAB, 0xCD, 0xEF, 0x01, 0x23, 0x45, 0x67, 0x89, 0xFF, 0x80, 0x00}
+	r := NewReader(src)
+	expect(t, uint8(0xA), ReadUint[uint8](&r, 4))
+	expect(t, tag(0xBCD), ReadUint[tag](&r, 12))
+	expect(t, uint64(0xEF0123456789), ReadUint[uint64](&r, 48))
+	expect(t, int8(-1), ReadInt[int8](&r, 8))
+	expect(t, -32768, ReadInt[int](&r, 16))
+	expect(t, nil, r.Error())
+	r = NewReader(src)
+	a, b := r, r
+	expect(t, a.Uint32(20), ReadUint[uint32](&b, 20))
+	expect(t, a.Int64(44), ReadInt[int64](&b, 44))
+	expect(t, a.At(), b.At())
+	expectPanic(t, ErrWidth, func() { ReadUint[uint8](&r, 9) })
+	expectPanic(t, ErrWidth, func() { ReadInt[int16](&r, 17) })
+	expect(t, uint(0), r.At())
+}
+
+func TestPutGeneric(t *testing.T) {
+	buf := make([]byte, 11)
+	w := NewWriter(buf)
+	PutUint(&w, 4, uint8(0xFA))
+	PutUint(&w, 12, tag(0xBCD))
+	PutUint(&w, 48, uint64(0xEF0123456789))
+	PutInt(&w, 8, int8(-1))
+	PutInt(&w, 16, -32768)
+	expect(t, nil, w.Flush())
+	expect(t, []byte{0xAB, 0xCD, 0xEF, 0x01, 0x23, 0x45, 0x67, 0x89, 0xFF, 0x80, 0x00}, buf)
+	ref := make([]byte, 8)
+	a, b := NewWriter(ref), NewWriter(buf)
+	a.PutInt32(20, -12345)
+	PutInt(&b, 20, int32(-12345))
+	a.PutUint64(44, 0x123456789ABCDEF)
+	PutUint(&b, 44, uint64(0x123456789ABCDEF))
+	expect(t, nil, a.Flush())
+	expect(t, nil, b.Flush())
+	expect(t, ref, buf[:8])
+	expectPanic(t, ErrWidth, func() { PutUint(&w, 9, uint8(0)) })
+	expectPanic(t, ErrWidth, func() { PutInt(&w, 33, int32(0)) })
+	expect(t, 88, w.Index())
+}
@@ -0,0 +1,3 @@
+module github.com/bamiaux/iobit
+
+go 1.21
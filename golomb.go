@@ -0,0 +1,85 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/bits"
+)
+
+// Unary reads a unary code made of n zero bits ended by a one bit and
+// returns n.
+// Flags ErrTooLong past the maximum code length & ErrTruncated if the
+// code runs past the reader end, returning the zeros counted so far.
+func (r *Reader) Unary() uint {
+	start := r.idx
+	n := uint(0)
+	for !r.Bit() {
+		n++
+		// the final one bit is still ahead
+		if r.codeTooLong(start-1) || r.codeTruncated() {
+			return n
+		}
+	}
+	r.codeTruncated()
+	return n
+}
+
+// PutUnary writes <n> zero bits followed by a one bit.
+func (w *Writer) PutUnary(n uint) {
+	for ; n >= 32; n -= 32 {
+		w.PutUint32(32, 0)
+	}
+	w.PutUint32(n+1, 1)
+}
+
+// Rice reads a Golomb-Rice code of parameter <k>, up to 32: a unary
+// quotient followed by a <k>-bit remainder.
+func (r *Reader) Rice(k uint) uint32 {
+	q := uint32(r.Unary())
+	return q<<k | r.Uint32(k)
+}
+
+// PutRice writes <val> as a Golomb-Rice code of parameter <k>, up to 32.
+func (w *Writer) PutRice(k uint, val uint32) {
+	w.PutUnary(uint(val >> k))
+	w.PutUint32(k, val)
+}
+
+// truncated returns the remainder size in bits & the threshold below
+// which remainders are coded on one bit less for divisor <m>
+func truncated(m uint32) (uint, uint32) {
+	b := uint(bits.Len32(m - 1))
+	return b, uint32(uint64(1)<<b - uint64(m))
+}
+
+// Golomb reads a Golomb code of positive divisor <m>: a unary quotient
+// followed by a truncated binary remainder.
+func (r *Reader) Golomb(m uint32) uint32 {
+	q := uint32(r.Unary())
+	b, t := truncated(m)
+	if b == 0 {
+		return q
+	}
+	rem := r.Uint32(b - 1)
+	if rem >= t {
+		rem = (rem<<1 | r.Uint32(1)) - t
+	}
+	return q*m + rem
+}
+
+// PutGolomb writes <val> as a Golomb code of positive divisor <m>.
+func (w *Writer) PutGolomb(m, val uint32) {
+	w.PutUnary(uint(val / m))
+	b, t := truncated(m)
+	if b == 0 {
+		return
+	}
+	rem := val % m
+	if rem < t {
+		w.PutUint32(b-1, rem)
+		return
+	}
+	w.PutUint32(b, rem+t)
+}
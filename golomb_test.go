@@ -0,0 +1,132 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnary(t *testing.T) {
+	values := []uint{0, 1, 2, 7, 31, 32, 33, 64, 100, 0}
+	buf := make([]byte, 64)
+	w := NewWriter(buf)
+	total := 0
+	for _, v := range values {
+		w.PutUnary(v)
+		total += int(v) + 1
+		expect(t, total, w.Index())
+	}
+	w.PutUint32(uint(-w.Index()&7), 0)
+	expect(t, nil, w.Flush())
+	expect(t, byte(0xA4), buf[0])
+	r := NewReader(buf)
+	for _, v := range values {
+		expect(t, v, r.Unary())
+	}
+	expect(t, nil, r.Error())
+	// running out of bits stops the count
+	for _, policy := range []OverflowPolicy{OverflowIgnore, OverflowSaturate} {
+		r = NewReaderPolicy([]byte{0x00, 0x00}, policy)
+		r.Unary()
+		expect(t, ErrTruncated, r.Error())
+	}
+	r = NewReader(make([]byte, 1024))
+	r.SetMaxCodeLength(16)
+	expect(t, uint(16), r.Unary())
+	expect(t, ErrTooLong, r.Error())
+	r = NewReader([]byte{0x00, 0x01})
+	r.SetMaxCodeLength(16)
+	expect(t, uint(15), r.Unary())
+	expect(t, nil, r.Error())
+}
+
+func TestRice(t *testing.T) {
+	// k = 2: 0 -> 100, 5 -> 0101, 9 -> 00101
+	buf := make([]byte, 2)
+	w := NewWriter(buf)
+	for _, v := range []uint32{0, 5, 9} {
+		w.PutRice(2, v)
+	}
+	w.PutUint32(4, 0)
+	expect(t, nil, w.Flush())
+	expect(t, []byte{0x8A, 0x50}, buf)
+	r := NewReader(buf)
+	expect(t, uint32(0), r.Rice(2))
+	expect(t, uint32(5), r.Rice(2))
+	expect(t, uint32(9), r.Rice(2))
+	src := makeSource(256)
+	for k := uint(0); k <= 32; k += 4 {
+		values := []uint32{}
+		r = NewReader(src)
+		for i := 0; i < 64; i++ {
+			v := r.Uint32(32)
+			// keep quotients small
+			values = append(values, v>>(32-min(k+6, 32)))
+		}
+		buf = make([]byte, 1024)
+		w = NewWriter(buf)
+		for _, v := range values {
+			w.PutRice(k, v)
+		}
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r = NewReader(buf)
+		for _, v := range values {
+			expect(t, v, r.Rice(k))
+		}
+		expect(t, nil, r.Error())
+	}
+	// k = 0 is unary
+	r = NewReader([]byte{0x11})
+	expect(t, uint32(3), r.Rice(0))
+	expect(t, uint(4), r.At())
+}
+
+func TestGolomb(t *testing.T) {
+	// remainder codes, preceded by the unary quotient
+	for m, codes := range map[uint32][]string{
+		1:  {""},
+		3:  {"0", "10", "11"},
+		4:  {"00", "01", "10", "11"},
+		5:  {"00", "01", "10", "110", "111"},
+		10: {"000", "001", "010", "011", "100", "101", "1100", "1101", "1110", "1111"},
+	} {
+		for i := uint32(0); i < 3*m; i++ {
+			code := strings.Repeat("0", int(i/m)) + "1" + codes[i%m]
+			v, n := refCode(code)
+			buf := make([]byte, 4)
+			w := NewWriter(buf)
+			w.PutGolomb(m, i)
+			expect(t, int(n), w.Index())
+			w.PutUint32(32-n, 0)
+			expect(t, nil, w.Flush())
+			r := NewReader(buf)
+			expect(t, v<<(32-n), r.PeekBits(32))
+			expect(t, i, r.Golomb(m))
+			expect(t, n, r.At())
+		}
+	}
+	values := []uint32{0, 1, 2, 1000}
+	for i := 0; i < 256; i++ {
+		values = append(values, uint32(rand.Intn(4096)))
+	}
+	for _, m := range []uint32{1, 7, 100, 1 << 31, 1<<31 + 1, math.MaxUint32} {
+		buf := make([]byte, 1<<18)
+		w := NewWriter(buf)
+		for _, v := range values {
+			w.PutGolomb(m, v)
+		}
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		for _, v := range values {
+			expect(t, v, r.Golomb(m))
+		}
+		expect(t, nil, r.Error())
+	}
+}
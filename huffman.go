@@ -0,0 +1,110 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"errors"
+)
+
+// ErrInvalidCode happens when code lengths don't form a complete prefix code
+// or when writing symbols without a code
+var ErrInvalidCode = errors.New("invalid prefix code")
+
+const maxHuffmanBits = 32
+
+// CanonicalHuffman encodes & decodes symbols with canonical huffman codes
+// as used by DEFLATE & JPEG.
+// Codes are assigned in increasing symbol order within each length and are
+// read & written most significant bit first.
+type CanonicalHuffman struct {
+	codes   []uint32
+	lengths []uint8
+	count   [maxHuffmanBits + 1]uint32 // number of codes per length
+	first   [maxHuffmanBits + 1]uint32 // first code per length
+	offset  [maxHuffmanBits + 1]uint32 // first symbol index per length
+	symbols []int                      // symbols sorted by code
+}
+
+// NewCanonicalHuffman builds canonical codes from per-symbol code lengths,
+// which are copied. A zero length means the symbol is unused.
+// Returns ErrInvalidCode if lengths exceed 32 bits or don't form a complete
+// prefix code.
+func NewCanonicalHuffman(lengths []uint8) (*CanonicalHuffman, error) {
+	h := &CanonicalHuffman{
+		codes:   make([]uint32, len(lengths)),
+		lengths: append([]uint8(nil), lengths...),
+	}
+	for _, n := range lengths {
+		if n > maxHuffmanBits {
+			return nil, ErrInvalidCode
+		}
+		h.count[n]++
+	}
+	h.count[0] = 0
+	// check the code is complete: the kraft sum must be exactly one
+	left := uint64(1)
+	for n := 1; n <= maxHuffmanBits; n++ {
+		left <<= 1
+		if uint64(h.count[n]) > left {
+			return nil, ErrInvalidCode
+		}
+		left -= uint64(h.count[n])
+	}
+	if left != 0 {
+		return nil, ErrInvalidCode
+	}
+	code := uint32(0)
+	idx := uint32(0)
+	for n := 1; n <= maxHuffmanBits; n++ {
+		code = (code + h.count[n-1]) << 1
+		h.first[n] = code
+		h.offset[n] = idx
+		idx += h.count[n]
+	}
+	h.symbols = make([]int, idx)
+	next := h.first
+	pos := h.offset
+	for sym, n := range lengths {
+		if n == 0 {
+			continue
+		}
+		h.codes[sym] = next[n]
+		next[n]++
+		h.symbols[pos[n]] = sym
+		pos[n]++
+	}
+	return h, nil
+}
+
+// Put writes the code of <symbol>.
+// Unused symbols have no code: they write nothing & flag ErrInvalidCode,
+// which Flush reports.
+func (h *CanonicalHuffman) Put(w *Writer, symbol int) {
+	n := h.lengths[symbol]
+	if n == 0 && w.err == nil {
+		w.err = ErrInvalidCode
+	}
+	w.PutUint32(uint(n), h.codes[symbol])
+}
+
+// Decode reads one code and returns its symbol.
+// Returns -1 if no code matches, once the maximum code length of <r> is
+// exceeded, which flags ErrTooLong, or if the code runs past the reader
+// end, which flags ErrTruncated.
+func (h *CanonicalHuffman) Decode(r *Reader) int {
+	code := uint32(0)
+	start := r.idx
+	for n := 1; n <= maxHuffmanBits; n++ {
+		code |= r.Uint32(1)
+		if r.codeTooLong(start) || r.codeTruncated() {
+			return -1
+		}
+		if delta := code - h.first[n]; code >= h.first[n] && delta < h.count[n] {
+			return h.symbols[h.offset[n]+delta]
+		}
+		code <<= 1
+	}
+	return -1
+}
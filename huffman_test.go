@@ -0,0 +1,91 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCanonicalHuffmanCodes(t *testing.T) {
+	// example from RFC 1951 section 3.2.2
+	h, err := NewCanonicalHuffman([]uint8{3, 3, 3, 3, 3, 2, 4, 4})
+	expect(t, nil, err)
+	expect(t, []uint32{2, 3, 4, 5, 6, 0, 14, 15}, h.codes)
+}
+
+func TestCanonicalHuffmanErrors(t *testing.T) {
+	for _, lengths := range [][]uint8{
+		{},
+		{0, 0},
+		{1},
+		{1, 1, 1},
+		{1, 2},
+		{2, 2, 2, 3},
+		{33, 1},
+	} {
+		_, err := NewCanonicalHuffman(lengths)
+		expect(t, ErrInvalidCode, err)
+	}
+	lengths := []uint8{0, 1, 2, 2}
+	h, err := NewCanonicalHuffman(lengths)
+	expect(t, nil, err)
+	// lengths are copied
+	lengths[0] = 1
+	expect(t, uint8(0), h.lengths[0])
+	// unused symbols have no code
+	buf := make([]byte, 1)
+	w := NewWriter(buf)
+	h.Put(&w, 1)
+	h.Put(&w, 0)
+	h.Put(&w, 3)
+	expect(t, 3, w.Index())
+	expect(t, ErrInvalidCode, w.Flush())
+	// codes running past the end are truncated
+	r := NewReader([]byte{0xE7})
+	expect(t, 3, h.Decode(&r))
+	expect(t, 2, h.Decode(&r))
+	expect(t, 1, h.Decode(&r))
+	expect(t, 3, h.Decode(&r))
+	expect(t, nil, r.Error())
+	expect(t, -1, h.Decode(&r))
+	expect(t, ErrTruncated, r.Error())
+	expect(t, uint(9), r.At())
+	r = NewReader(nil)
+	expect(t, -1, h.Decode(&r))
+	expect(t, ErrTruncated, r.Error())
+}
+
+func TestCanonicalHuffmanRoundTrip(t *testing.T) {
+	for _, lengths := range [][]uint8{
+		{1, 1},
+		{3, 3, 3, 3, 3, 2, 4, 4},
+		{0, 2, 0, 2, 2, 3, 4, 5, 6, 6, 0},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+			20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 32},
+	} {
+		h, err := NewCanonicalHuffman(lengths)
+		expect(t, nil, err)
+		var symbols []int
+		for len(symbols) < 1024 {
+			sym := rand.Intn(len(lengths))
+			if lengths[sym] != 0 {
+				symbols = append(symbols, sym)
+			}
+		}
+		buf := make([]byte, 1024*4+1)
+		w := NewWriter(buf)
+		for _, sym := range symbols {
+			h.Put(&w, sym)
+		}
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		for _, sym := range symbols {
+			expect(t, sym, h.Decode(&r))
+		}
+		expect(t, nil, r.Error())
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// LookaheadReader decodes symbols ahead of the reader position and caches
+// them, so looking at upcoming symbols never decodes them twice.
+// Cached symbols are only valid for the decode function which produced
+// them: call Flush before switching to another one.
+type LookaheadReader struct {
+	r       *Reader
+	symbols []uint32
+	ends    []uint // reader position after each cached symbol
+}
+
+// NewLookaheadReader returns a new lookahead reader reading from <r>.
+func NewLookaheadReader(r *Reader) *LookaheadReader {
+	return &LookaheadReader{r: r}
+}
+
+// PeekSymbol returns the <n>th upcoming symbol, starting at zero, without
+// advancing the reader.
+func (l *LookaheadReader) PeekSymbol(n int, decode func(*Reader) uint32) uint32 {
+	for len(l.symbols) <= n {
+		p := l.r.Peek()
+		if len(l.ends) > 0 {
+			p.idx = l.ends[len(l.ends)-1]
+		}
+		l.symbols = append(l.symbols, decode(p))
+		l.ends = append(l.ends, p.idx)
+	}
+	return l.symbols[n]
+}
+
+// Next returns the next symbol and advances the reader past it.
+func (l *LookaheadReader) Next(decode func(*Reader) uint32) uint32 {
+	if len(l.symbols) == 0 {
+		return decode(l.r)
+	}
+	v := l.symbols[0]
+	l.r.idx = l.ends[0]
+	l.symbols = l.symbols[1:]
+	l.ends = l.ends[1:]
+	return v
+}
+
+// Flush drops every cached symbol.
+func (l *LookaheadReader) Flush() {
+	l.symbols = l.symbols[:0]
+	l.ends = l.ends[:0]
+}
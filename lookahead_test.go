@@ -0,0 +1,45 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLookaheadReader(t *testing.T) {
+	values := make([]uint32, 256)
+	buf := make([]byte, len(values)*8)
+	w := NewWriter(buf)
+	for i := range values {
+		values[i] = rand.Uint32() >> rand.Intn(32)
+		w.PutInterleavedExpGolomb(values[i])
+	}
+	w.PutUint32(uint(-w.Index()&7), 0)
+	expect(t, nil, w.Flush())
+	calls := 0
+	decode := func(r *Reader) uint32 {
+		calls++
+		return r.InterleavedExpGolomb()
+	}
+	r := NewReader(buf)
+	l := NewLookaheadReader(&r)
+	for i := 0; i < len(values); {
+		ahead := rand.Intn(4)
+		for j := ahead; j >= 0 && i+j < len(values); j-- {
+			expect(t, values[i+j], l.PeekSymbol(j, decode))
+		}
+		expect(t, values[i], l.Next(decode))
+		ref := NewReader(buf)
+		for j := 0; j <= i; j++ {
+			ref.InterleavedExpGolomb()
+		}
+		expect(t, ref.At(), r.At())
+		i++
+	}
+	expect(t, true, calls <= len(values)+4)
+	l.Flush()
+	expect(t, nil, r.Error())
+}
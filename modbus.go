@@ -0,0 +1,63 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+)
+
+// RegisterOrder selects how the bytes of a 32-bit value are spread over two
+// Modbus registers, letters naming bytes from the most significant.
+type RegisterOrder int
+
+const (
+	// RegisterABCD sends bytes in big-endian order
+	RegisterABCD RegisterOrder = iota
+	// RegisterDCBA sends bytes in little-endian order
+	RegisterDCBA
+	// RegisterBADC sends big-endian words with swapped bytes
+	RegisterBADC
+	// RegisterCDAB sends big-endian words, low word first
+	RegisterCDAB
+)
+
+// swap converts between big-endian and register order, both ways.
+func (o RegisterOrder) swap(v uint32) uint32 {
+	switch o {
+	case RegisterDCBA:
+		return bswap32(v)
+	case RegisterBADC:
+		return v&0x00FF00FF<<8 | v>>8&0x00FF00FF
+	case RegisterCDAB:
+		return v<<16 | v>>16
+	}
+	return v
+}
+
+// Uint32WordSwap reads 32 unsigned bits stored as two big-endian 16-bit
+// words, low word first, as Modbus devices commonly transport them.
+// Such values are expected at byte alignment.
+func (r *Reader) Uint32WordSwap() uint32 {
+	return RegisterCDAB.swap(r.Be32())
+}
+
+// PutUint32WordSwap writes 32 bits as two big-endian 16-bit words, low word
+// first.
+func (w *Writer) PutUint32WordSwap(val uint32) {
+	w.PutBe32(RegisterCDAB.swap(val))
+}
+
+// ModbusFloat32 reads a 32-bit float spread over two Modbus registers in
+// <order>.
+// Such values are expected at byte alignment.
+func (r *Reader) ModbusFloat32(order RegisterOrder) float32 {
+	return math.Float32frombits(order.swap(r.Be32()))
+}
+
+// PutModbusFloat32 writes a 32-bit float spread over two Modbus registers in
+// <order>.
+func (w *Writer) PutModbusFloat32(order RegisterOrder, val float32) {
+	w.PutBe32(order.swap(math.Float32bits(val)))
+}
@@ -0,0 +1,44 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUint32WordSwap(t *testing.T) {
+	// 123.456 as float32 is 0x42F6E979, sent as CDAB
+	src := []byte{0xE9, 0x79, 0x42, 0xF6}
+	r := NewReader(src)
+	expect(t, uint32(0x42F6E979), r.Uint32WordSwap())
+	expect(t, nil, r.Error())
+	dst := make([]byte, len(src))
+	w := NewWriter(dst)
+	w.PutUint32WordSwap(0x42F6E979)
+	expect(t, nil, w.Flush())
+	expect(t, true, bytes.Equal(src, dst))
+}
+
+func TestModbusFloat32(t *testing.T) {
+	for _, v := range []struct {
+		order RegisterOrder
+		src   []byte
+	}{
+		{RegisterABCD, []byte{0x42, 0xF6, 0xE9, 0x79}},
+		{RegisterDCBA, []byte{0x79, 0xE9, 0xF6, 0x42}},
+		{RegisterBADC, []byte{0xF6, 0x42, 0x79, 0xE9}},
+		{RegisterCDAB, []byte{0xE9, 0x79, 0x42, 0xF6}},
+	} {
+		r := NewReader(v.src)
+		expect(t, float32(123.456), r.ModbusFloat32(v.order))
+		expect(t, nil, r.Error())
+		dst := make([]byte, len(v.src))
+		w := NewWriter(dst)
+		w.PutModbusFloat32(v.order, 123.456)
+		expect(t, nil, w.Flush())
+		compare(t, v.src, dst)
+	}
+}
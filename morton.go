@@ -0,0 +1,74 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// Morton codes interleave coordinate bits with x in the least significant
+// position, i.e. ...y1x1y0x0 in 2D & ...z1y1x1z0y0x0 in 3D.
+
+func spread2(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+func compact2(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return uint32(x)
+}
+
+func spread3(v uint32) uint64 {
+	x := uint64(v) & 0x1FFFFF
+	x = (x | x<<32) & 0x001F00000000FFFF
+	x = (x | x<<16) & 0x001F0000FF0000FF
+	x = (x | x<<8) & 0x100F00F00F00F00F
+	x = (x | x<<4) & 0x10C30C30C30C30C3
+	x = (x | x<<2) & 0x1249249249249249
+	return x
+}
+
+func compact3(x uint64) uint32 {
+	x &= 0x1249249249249249
+	x = (x | x>>2) & 0x10C30C30C30C30C3
+	x = (x | x>>4) & 0x100F00F00F00F00F
+	x = (x | x>>8) & 0x001F0000FF0000FF
+	x = (x | x>>16) & 0x001F00000000FFFF
+	x = (x | x>>32) & 0x00000000001FFFFF
+	return uint32(x)
+}
+
+// Morton2D reads a 2*bits morton code and deinterleaves it.
+// <bits> is the width of each coordinate, up to 32.
+func (r *Reader) Morton2D(bits uint) (x, y uint32) {
+	m := r.Uint64(2 * bits)
+	return compact2(m), compact2(m >> 1)
+}
+
+// Morton3D reads a 3*bits morton code and deinterleaves it.
+// <bits> is the width of each coordinate, up to 21.
+func (r *Reader) Morton3D(bits uint) (x, y, z uint32) {
+	m := r.Uint64(3 * bits)
+	return compact3(m), compact3(m >> 1), compact3(m >> 2)
+}
+
+// PutMorton2D interleaves two coordinates of <bits> each, up to 32, and
+// writes the resulting 2*bits morton code.
+func (w *Writer) PutMorton2D(bits uint, x, y uint32) {
+	w.PutUint64(2*bits, spread2(x)|spread2(y)<<1)
+}
+
+// PutMorton3D interleaves three coordinates of <bits> each, up to 21, and
+// writes the resulting 3*bits morton code.
+func (w *Writer) PutMorton3D(bits uint, x, y, z uint32) {
+	w.PutUint64(3*bits, spread3(x)|spread3(y)<<1|spread3(z)<<2)
+}
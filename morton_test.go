@@ -0,0 +1,49 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMortonCodes(t *testing.T) {
+	buf := []byte{0x78}
+	r := NewReader(buf)
+	// 0b011110 = y2x2y1x1y0x0 with x = 0b110 & y = 0b011
+	x, y := r.Peek().Morton2D(3)
+	expect(t, uint32(6), x)
+	expect(t, uint32(3), y)
+	// 0b000111 = z1y1x1z0y0x0 with x = y = z = 0b01
+	buf = []byte{0x1C}
+	r = NewReader(buf)
+	x, y, z := r.Morton3D(2)
+	expect(t, []uint32{1, 1, 1}, []uint32{x, y, z})
+}
+
+func TestMortonRoundTrip(t *testing.T) {
+	buf := make([]byte, 64*16)
+	for bits := uint(1); bits <= 32; bits++ {
+		mask := uint32(1<<bits - 1)
+		var coords []uint32
+		w := NewWriter(buf)
+		for i := 0; i < 16; i++ {
+			x, y, z := rand.Uint32()&mask, rand.Uint32()&mask, rand.Uint32()&mask&0x1FFFFF
+			coords = append(coords, x, y, z)
+			w.PutMorton2D(bits, x, y)
+			w.PutMorton3D(min(bits, 21), x&0x1FFFFF, y&0x1FFFFF, z)
+		}
+		w.PutUint32(uint(-w.Index()&7), 0)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		for i := 0; i < len(coords); i += 3 {
+			x, y := r.Morton2D(bits)
+			expect(t, coords[i:i+2], []uint32{x, y})
+			x, y, z := r.Morton3D(min(bits, 21))
+			expect(t, []uint32{coords[i] & 0x1FFFFF, coords[i+1] & 0x1FFFFF, coords[i+2]}, []uint32{x, y, z})
+		}
+		expect(t, nil, r.Error())
+	}
+}
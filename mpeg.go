@@ -0,0 +1,59 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// PCR reads an MPEG-TS program clock reference: a 33-bit base, 6 reserved
+// bits & a 9-bit extension.
+func (r *Reader) PCR() (base uint64, ext uint16) {
+	base = r.Uint64(33)
+	r.Skip(6)
+	ext = r.Uint16(9)
+	return
+}
+
+// PTS reads an MPEG PES timestamp: a 4-bit prefix followed by a 33-bit
+// value split in 3, 15 & 15 bits, each part ended by a marker bit.
+// Flags ErrReserved if any marker bit is not set, unless the read already
+// failed.
+func (r *Reader) PTS() (prefix uint8, pts uint64) {
+	prefix = r.Uint8(4)
+	pts = uint64(r.Uint32(3)) << 30
+	markers := r.Uint32(1)
+	pts |= uint64(r.Uint32(15)) << 15
+	markers <<= 1
+	markers |= r.Uint32(1)
+	pts |= uint64(r.Uint32(15))
+	markers <<= 1
+	markers |= r.Uint32(1)
+	if markers != 7 && r.Error() == nil {
+		r.err = ErrReserved
+	}
+	return
+}
+
+// DTS reads an MPEG PES decoding timestamp, which shares the PTS layout.
+func (r *Reader) DTS() (prefix uint8, dts uint64) {
+	return r.PTS()
+}
+
+// PutPCR writes an MPEG-TS program clock reference with its reserved bits
+// set.
+func (w *Writer) PutPCR(base uint64, ext uint16) {
+	w.PutUint64(33, base)
+	w.PutReserved(6, true)
+	w.PutUint16(9, ext)
+}
+
+// PutPTS writes an MPEG PES timestamp with its marker bits.
+// Use the same layout for decoding timestamps.
+func (w *Writer) PutPTS(prefix uint8, pts uint64) {
+	w.PutUint8(4, prefix)
+	w.PutUint32(3, uint32(pts>>30))
+	w.PutBit(true)
+	w.PutUint32(15, uint32(pts>>15))
+	w.PutBit(true)
+	w.PutUint32(15, uint32(pts))
+	w.PutBit(true)
+}
@@ -0,0 +1,81 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestPCR(t *testing.T) {
+	r := NewReader([]byte{0x00, 0x01, 0x5F, 0x90, 0x7E, 0x96})
+	base, ext := r.PCR()
+	expect(t, uint64(180000), base)
+	expect(t, uint16(150), ext)
+	r = NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x2B})
+	base, ext = r.PCR()
+	expect(t, uint64(0x1FFFFFFFF), base)
+	expect(t, uint16(299), ext)
+	expect(t, uint(48), r.At())
+}
+
+func TestPTS(t *testing.T) {
+	r := NewReader([]byte{0x21, 0x00, 0x07, 0xD8, 0x61})
+	prefix, pts := r.PTS()
+	expect(t, uint8(2), prefix)
+	expect(t, uint64(126000), pts)
+	expect(t, nil, r.Error())
+	r = NewReader([]byte{
+		0x3F, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x19, 0x8D, 0x15, 0xCF, 0x13,
+	})
+	prefix, pts = r.PTS()
+	expect(t, uint8(3), prefix)
+	expect(t, uint64(0x1FFFFFFFF), pts)
+	prefix, dts := r.DTS()
+	expect(t, uint8(1), prefix)
+	expect(t, uint64(0x123456789), dts)
+	expect(t, nil, r.Error())
+	// missing marker bit
+	r = NewReader([]byte{0x21, 0x00, 0x07, 0xD8, 0x60})
+	prefix, pts = r.PTS()
+	expect(t, uint64(126000), pts)
+	expect(t, ErrReserved, r.Error())
+	// truncated
+	r = NewReader([]byte{0x21, 0x00})
+	r.PTS()
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestPutPCR(t *testing.T) {
+	buf := make([]byte, 12)
+	w := NewWriter(buf)
+	w.PutPCR(180000, 150)
+	w.PutPCR(0x1FFFFFFFF, 299)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{
+		0x00, 0x01, 0x5F, 0x90, 0x7E, 0x96,
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x2B,
+	}, buf)
+}
+
+func TestPutPTS(t *testing.T) {
+	buf := make([]byte, 15)
+	w := NewWriter(buf)
+	w.PutPTS(2, 126000)
+	w.PutPTS(3, 0x1FFFFFFFF)
+	w.PutPTS(1, 0x123456789)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{
+		0x21, 0x00, 0x07, 0xD8, 0x61,
+		0x3F, 0xFF, 0xFF, 0xFF, 0xFF,
+		0x19, 0x8D, 0x15, 0xCF, 0x13,
+	}, buf)
+	r := NewReader(buf)
+	for _, v := range []uint64{126000, 0x1FFFFFFFF, 0x123456789} {
+		_, pts := r.PTS()
+		expect(t, v, pts)
+	}
+	expect(t, nil, r.Error())
+}
@@ -0,0 +1,55 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// NALIterator splits an H.264/H.265 Annex-B byte stream into NAL units.
+// Bytes before the first start code are ignored, zero bytes trailing a
+// NAL unit are trimmed & empty NAL units are skipped.
+type NALIterator struct {
+	src []byte
+	idx int
+}
+
+// NewNALIterator returns a new iterator over the Annex-B stream <src>.
+func NewNALIterator(src []byte) NALIterator {
+	it := NALIterator{src: src, idx: len(src)}
+	if i := it.find(0); i >= 0 {
+		it.idx = i + 3
+	}
+	return it
+}
+
+// find returns the offset of the first start code at or after <idx>, or -1.
+func (it *NALIterator) find(idx int) int {
+	for i := idx; i+2 < len(it.src); i++ {
+		if it.src[i+2] > 1 {
+			i += 2
+		} else if it.src[i] == 0 && it.src[i+1] == 0 && it.src[i+2] == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Next returns the next NAL unit, without its start code, & false once the
+// stream is exhausted. The returned slice aliases the source buffer.
+func (it *NALIterator) Next() (nal []byte, ok bool) {
+	for it.idx < len(it.src) {
+		begin := it.idx
+		end := len(it.src)
+		it.idx = end
+		if i := it.find(begin); i >= 0 {
+			end = i
+			it.idx = i + 3
+		}
+		for end > begin && it.src[end-1] == 0 {
+			end--
+		}
+		if end > begin {
+			return it.src[begin:end], true
+		}
+	}
+	return nil, false
+}
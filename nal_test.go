@@ -0,0 +1,46 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNALIterator(t *testing.T) {
+	stream := []byte{
+		0xAA, 0xBB, // leading garbage
+		0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x1E,
+		0x00, 0x00, 0x01, 0x68, 0xCE, 0x00, 0x03, 0x80,
+		0x00, 0x00, 0x01, // empty nal
+		0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x00, 0x00, // trailing zeros
+		0x00, 0x00, 0x01, 0x06, 0x05, 0xFF, 0x00, 0x02, // trailing garbage
+	}
+	want := [][]byte{
+		{0x67, 0x42, 0x00, 0x1E},
+		{0x68, 0xCE, 0x00, 0x03, 0x80},
+		{0x65, 0x88, 0x84},
+		{0x06, 0x05, 0xFF, 0x00, 0x02},
+	}
+	it := NewNALIterator(stream)
+	for i, w := range want {
+		nal, ok := it.Next()
+		if !ok || !bytes.Equal(w, nal) {
+			t.Fatalf("nal %d: got %x %v, want %x", i, nal, ok, w)
+		}
+	}
+	nal, ok := it.Next()
+	expect(t, false, ok)
+	expect(t, 0, len(nal))
+	for _, src := range [][]byte{nil, {0x00, 0x00}, {0x12, 0x34, 0x56}, {0x00, 0x00, 0x01}} {
+		it = NewNALIterator(src)
+		_, ok = it.Next()
+		expect(t, false, ok)
+	}
+	it = NewNALIterator([]byte{0x00, 0x00, 0x01, 0x09})
+	nal, ok = it.Next()
+	expect(t, true, ok)
+	expect(t, true, bytes.Equal([]byte{0x09}, nal))
+}
@@ -0,0 +1,104 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"encoding/binary"
+)
+
+// ByteOrder selects how the bytes of a field are assembled.
+type ByteOrder int
+
+// BitOrder selects in which order bits are consumed within each byte.
+type BitOrder int
+
+const (
+	// ByteBE makes the first byte of a field its most significant
+	ByteBE ByteOrder = iota
+	// ByteLE makes the first byte of a field its least significant
+	ByteLE
+)
+
+const (
+	// BitMSB consumes each byte from its most significant bit
+	BitMSB BitOrder = iota
+	// BitLSB consumes each byte from its least significant bit
+	BitLSB
+)
+
+const (
+	orderByteLE = 1 << iota
+	orderBitLSB
+)
+
+// NewReaderOrder returns a new reader reading from <src> with independent
+// byte & bit orders.
+// Fields are split in groups of 8 bits from their first bit, the last one
+// possibly shorter. Each group is consumed in <bits> order, the first bit
+// being the most significant with BitMSB or the least significant with
+// BitLSB. Groups are then assembled in <bytes> order.
+// ByteBE & BitMSB is the regular big-endian reader, while ByteLE & BitLSB
+// reads fields as packed by DEFLATE.
+// Every generic read & FieldCursor follow the configured order. Bytes,
+// String, RawBits & ConstantTimeUint32 always read raw big-endian bits.
+func NewReaderOrder(src []byte, bytes ByteOrder, bits BitOrder) Reader {
+	r := NewReader(src)
+	if bytes == ByteLE {
+		r.order |= orderByteLE
+	}
+	if bits == BitLSB {
+		r.order |= orderBitLSB
+	}
+	if r.order != 0 {
+		// ordered reads always take the slow path
+		r.fast = 0
+	}
+	return r
+}
+
+// NewReaderLSB returns a new reader reading from <src> least significant
+// bit first, as packed by DEFLATE: each field is filled from its low end
+// with bits taken from the low end of each byte.
+// It is a shortcut for NewReaderOrder with ByteLE & BitLSB.
+func NewReaderLSB(src []byte) Reader {
+	return NewReaderOrder(src, ByteLE, BitLSB)
+}
+
+// readOrdered reads up to 32 bits following reader orders
+func (r *Reader) readOrdered(bits uint) uint64 {
+	var val, out uint64
+	if r.policy != OverflowIgnore && r.drop(bits) {
+		return 0
+	}
+	if r.order&orderBitLSB == 0 {
+		// first bit is the most significant: groups start at the top
+		val = r.getSlow64(bits) >> (64 - bits)
+		if r.order&orderByteLE == 0 {
+			return val
+		}
+		for n, shift := bits, uint(0); n > 0; {
+			k := min(n, 8)
+			n -= k
+			out |= (val >> n & (1<<k - 1)) << shift
+			shift += k
+		}
+		return out
+	}
+	// first bit is the least significant: groups start at the bottom
+	skip := min(r.idx>>3, r.max)
+	val = binary.LittleEndian.Uint64(r.src[skip:]) >> (r.idx - skip<<3)
+	val &= 1<<min(bits, r.end-min(r.idx, r.end)) - 1
+	r.idx += bits
+	if r.order&orderByteLE != 0 {
+		return val
+	}
+	for n := bits; n > 0; {
+		k := min(n, 8)
+		n -= k
+		out = out<<k | val&(1<<k-1)
+		val >>= k
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// refOrdered reads <bits> at <idx> one bit at a time
+func refOrdered(src []byte, idx, bits uint, bytes ByteOrder, order BitOrder) uint64 {
+	var out uint64
+	shift := uint(0)
+	for n := uint(0); n < bits; n += 8 {
+		k := min(bits-n, 8)
+		var group uint64
+		for j := uint(0); j < k; j++ {
+			i := idx + n + j
+			var bit uint64
+			if i < uint(len(src)*8) {
+				if order == BitMSB {
+					bit = uint64(src[i>>3] >> (7 - i&7) & 1)
+				} else {
+					bit = uint64(src[i>>3] >> (i & 7) & 1)
+				}
+			}
+			if order == BitMSB {
+				group = group<<1 | bit
+			} else {
+				group |= bit << j
+			}
+		}
+		if bytes == ByteBE {
+			out = out<<k | group
+		} else {
+			out |= group << shift
+			shift += k
+		}
+	}
+	return out
+}
+
+func TestReaderOrders(t *testing.T) {
+	for _, size := range []int{0, 3, 8, 17, 64} {
+		src := makeSource(size)
+		for _, bytes := range []ByteOrder{ByteBE, ByteLE} {
+			for _, order := range []BitOrder{BitMSB, BitLSB} {
+				r := NewReaderOrder(src, bytes, order)
+				for r.At() < uint(size*8)+70 {
+					idx := r.At()
+					bits := uint(rand.Intn(65))
+					ref := refOrdered(src, idx, bits, bytes, order)
+					switch {
+					case bits == 1:
+						expect(t, ref != 0, r.Peek().Bit())
+					case bits <= 32:
+						expect(t, uint32(ref), r.Peek().Uint32(bits))
+						expect(t, int32(int64(ref<<(64-bits))>>(64-bits)), r.Peek().Int32(bits))
+					}
+					expect(t, ref, r.Peek().Uint64(bits))
+					expect(t, int64(ref<<(64-bits))>>(64-bits), r.Int64(bits))
+					expect(t, idx+bits, r.At())
+				}
+			}
+		}
+	}
+}
+
+func TestReaderOrderBytes(t *testing.T) {
+	buf := []byte{0x01, 0x02, 0x03, 0x04}
+	for _, order := range []BitOrder{BitMSB, BitLSB} {
+		r := NewReaderOrder(buf, ByteBE, order)
+		expect(t, uint32(0x01020304), r.Uint32(32))
+		r = NewReaderOrder(buf, ByteLE, order)
+		expect(t, uint32(0x04030201), r.Uint32(32))
+	}
+	// DEFLATE stores a fixed huffman block header as BFINAL=1, BTYPE=01
+	// in the low bits of the first byte
+	r := NewReaderOrder([]byte{0x03}, ByteLE, BitLSB)
+	expect(t, true, r.Bit())
+	expect(t, uint8(1), r.Uint8(2))
+}
+
+func TestReaderLSB(t *testing.T) {
+	// "hello" compressed by zlib as a single fixed huffman block
+	r := NewReaderLSB([]byte{0xCB, 0x48, 0xCD, 0xC9, 0xC9, 0x07, 0x00})
+	expect(t, uint32(1), r.Uint32(1)) // BFINAL
+	expect(t, uint32(1), r.Uint32(2)) // BTYPE
+	text := []byte{}
+	for {
+		// huffman codes are packed starting from their most significant bit
+		code := uint32(0)
+		for i := 0; i < 7; i++ {
+			code = code<<1 | r.Uint32(1)
+		}
+		if code == 0 {
+			break // end of block
+		}
+		code = code<<1 | r.Uint32(1)
+		expect(t, true, code >= 0x30 && code < 0xC0)
+		text = append(text, byte(code-0x30))
+	}
+	expect(t, "hello", string(text))
+	expect(t, uint(50), r.At())
+	expect(t, nil, r.Error())
+	r = NewReaderLSB([]byte{0xB4, 0x01})
+	expect(t, uint32(0x4), r.Uint32(3))
+	expect(t, uint32(0x36), r.Uint32(6))
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// UnpackFixed fills <dst> with consecutive unsigned values of <bits> bits,
+// up to 32, in big-endian order.
+// Every 64-bit load extracts as many whole values as it holds, which is
+// much faster than one Uint32 call per value for small widths.
+func (r *Reader) UnpackFixed(bits uint, dst []uint32) {
+	i := 0
+	if r.order == 0 && r.policy == OverflowIgnore && bits > 0 {
+		// values per load, allowing for up to 7 bits of misalignment
+		per := int(57 / bits)
+		span := uint(per) * bits
+		for i+per <= len(dst) {
+			skip := r.idx >> 3
+			// values near the end go through the checked path
+			if skip > r.max || r.idx+span > r.end {
+				break
+			}
+			val := binary.BigEndian.Uint64(r.src[skip:]) << (r.idx & 7)
+			for end := i + per; i < end; i++ {
+				dst[i] = uint32(val >> (64 - bits))
+				val <<= bits
+			}
+			r.idx += span
+		}
+	}
+	for ; i < len(dst); i++ {
+		dst[i] = r.Uint32(bits)
+	}
+}
+
+// PackFixed writes every value of <src> as <bits> unsigned bits, up to 32,
+// in big-endian order.
+// Values are gathered into 32-bit words before being written, which is
+// much faster than one PutUint32 call per value for small widths.
+func (w *Writer) PackFixed(bits uint, src []uint32) {
+	if bits == 0 {
+		return
+	}
+	per := int(32 / bits)
+	mask := uint32(1)<<bits - 1
+	i := 0
+	for ; per > 1 && i+per <= len(src); i += per {
+		acc := uint32(0)
+		for _, v := range src[i : i+per] {
+			acc = acc<<bits | v&mask
+		}
+		w.PutUint32(uint(per)*bits, acc)
+	}
+	for _, v := range src[i:] {
+		w.PutUint32(bits, v)
+	}
+}
+
+// BitLenHistogram returns how many values of <vals> need each bit length,
+// zero needing none, to help choosing fixed widths or Rice parameters.
+func BitLenHistogram(vals []uint64) [65]int {
+	var h [65]int
+	for _, v := range vals {
+		h[bits.Len64(v)]++
+	}
+	return h
+}
@@ -0,0 +1,151 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUnpackFixed(t *testing.T) {
+	src := makeSource(256)
+	for bits := uint(0); bits <= 32; bits++ {
+		for _, skip := range []uint{0, 1, 7, 13} {
+			n := int((uint(len(src)*8) - skip) / max(bits, 1))
+			for _, count := range []int{0, 1, 9, n, n + 3} {
+				r := NewReader(src)
+				r.Skip(skip)
+				ref := r
+				dst := make([]uint32, count)
+				r.UnpackFixed(bits, dst)
+				for i := range dst {
+					expect(t, ref.Uint32(bits), dst[i])
+				}
+				expect(t, ref.At(), r.At())
+				expect(t, ref.Error(), r.Error())
+			}
+		}
+	}
+	// shorter ends read zeros & flag overflows like Uint32
+	for _, bits := range []uint{1, 3, 8, 11, 32} {
+		r := NewReaderLimit(src, 301)
+		ref := NewReaderLimit(src, 301)
+		dst := make([]uint32, 400/bits)
+		r.UnpackFixed(bits, dst)
+		for i := range dst {
+			expect(t, ref.Uint32(bits), dst[i])
+		}
+		expect(t, ref.At(), r.At())
+		expect(t, ErrOverflow, r.Error())
+		r = NewReader(src)
+		ref = NewReader(src)
+		release, refRelease := r.Limit(77), ref.Limit(77)
+		r.UnpackFixed(bits, dst)
+		for i := range dst {
+			expect(t, ref.Uint32(bits), dst[i])
+		}
+		release()
+		refRelease()
+		expect(t, ErrOverflow, r.Error())
+	}
+}
+
+func TestPackFixed(t *testing.T) {
+	src := makeSource(256)
+	for bits := uint(0); bits <= 32; bits++ {
+		for _, skip := range []uint{0, 1, 7, 13} {
+			count := int((uint(len(src)*8) - skip) / max(bits, 1))
+			values := make([]uint32, count)
+			r := NewReader(src)
+			r.Skip(skip)
+			r.UnpackFixed(bits, values)
+			// bits above width must be ignored
+			for i := range values {
+				if bits < 32 {
+					values[i] |= 1 << bits
+				}
+			}
+			dst := make([]byte, len(src))
+			w := NewWriter(dst)
+			w.PutUint32(skip, 0)
+			w.PackFixed(bits, values)
+			expect(t, skip+uint(count)*bits, uint(w.Index()))
+			w.PutUint32(uint(-w.Index()&7), 0)
+			expect(t, nil, w.Flush())
+			r = NewReader(dst)
+			r.Skip(skip)
+			got := make([]uint32, count)
+			r.UnpackFixed(bits, got)
+			for i := range got {
+				expect(t, values[i]&(1<<bits-1), got[i])
+			}
+		}
+	}
+}
+
+func TestBitLenHistogram(t *testing.T) {
+	h := BitLenHistogram([]uint64{0, 1, 2, 3, 4, 7, 8, 255, 256, 1 << 63, ^uint64(0)})
+	var ref [65]int
+	ref[0] = 1
+	ref[1] = 1
+	ref[2] = 2
+	ref[3] = 2
+	ref[4] = 1
+	ref[8] = 1
+	ref[9] = 1
+	ref[64] = 2
+	expect(t, ref, h)
+	expect(t, [65]int{}, BitLenHistogram(nil))
+}
+
+var packWidths = []uint{3, 5, 7, 11, 17}
+
+func BenchmarkUnpackFixed(b *testing.B) {
+	buf := makeSource(4096)
+	for _, bits := range packWidths {
+		dst := make([]uint32, uint(len(buf)*8)/bits)
+		b.Run(fmt.Sprintf("uint32/%v", bits), func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			for i := 0; i < b.N; i++ {
+				r := NewReader(buf)
+				for j := range dst {
+					dst[j] = r.Uint32(bits)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("unpack/%v", bits), func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			for i := 0; i < b.N; i++ {
+				r := NewReader(buf)
+				r.UnpackFixed(bits, dst)
+			}
+		})
+	}
+}
+
+func BenchmarkPackFixed(b *testing.B) {
+	buf := make([]byte, 4096)
+	for _, bits := range packWidths {
+		src := make([]uint32, uint(len(buf)*8)/bits)
+		r := NewReader(makeSource(len(buf)))
+		r.UnpackFixed(bits, src)
+		b.Run(fmt.Sprintf("uint32/%v", bits), func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			for i := 0; i < b.N; i++ {
+				w := NewWriter(buf)
+				for _, v := range src {
+					w.PutUint32(bits, v)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("pack/%v", bits), func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			for i := 0; i < b.N; i++ {
+				w := NewWriter(buf)
+				w.PackFixed(bits, src)
+			}
+		})
+	}
+}
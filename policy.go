@@ -0,0 +1,97 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// OverflowPolicy selects how readers & writers handle overflows.
+type OverflowPolicy int
+
+const (
+	// OverflowIgnore reads zeros or drops writes past the end but advances
+	// anyway. Overflows are reported by Error or Flush.
+	// This is the default & fastest policy.
+	OverflowIgnore OverflowPolicy = iota
+	// OverflowPanic panics with ErrOverflow on the first overflowing
+	// operation.
+	OverflowPanic
+	// OverflowSaturate skips overflowing operations without advancing,
+	// reads returning zero. Overflows are still reported by Error or Flush.
+	OverflowSaturate
+	// overflowStrict reads zeros past the end & advances like
+	// OverflowIgnore but flags ErrPadding right away, see NewReaderStrict.
+	overflowStrict
+)
+
+// NewReaderPolicy returns a new reader reading from <src> and handling
+// overflows with <policy>.
+// Policies apply to every read but the byte-aligned LeftBytes, RawBits
+// & ConstantTimeUint32 helpers.
+func NewReaderPolicy(src []byte, policy OverflowPolicy) Reader {
+	r := NewReader(src)
+	r.policy = policy
+	if policy != OverflowIgnore {
+		// every read checks for overflows on the slow path
+		r.fast = 0
+	}
+	return r
+}
+
+// NewReaderStrict returns a new reader reading from <src> which flags
+// ErrPadding as soon as a read touches the zero padding added to inputs
+// shorter than 8 bytes.
+// Such reads still return the padding bits & advance, but Error tells
+// them apart from overflows. Reads past the padding, or past the end of
+// longer inputs, report ErrOverflow like other readers.
+func NewReaderStrict(src []byte) Reader {
+	return NewReaderPolicy(src, overflowStrict)
+}
+
+// drop applies the overflow policy and returns whether a read of <bits>
+// must be dropped
+func (r *Reader) drop(bits uint) bool {
+	if r.idx+bits <= r.end {
+		return false
+	}
+	switch r.policy {
+	case OverflowPanic:
+		panic(ErrOverflow)
+	case OverflowSaturate:
+		r.err = ErrOverflow
+		return true
+	case overflowStrict:
+		// only short inputs are padded, up to 64 bits
+		if r.padded && r.idx < 64 && r.err == nil {
+			r.err = ErrPadding
+		}
+	}
+	return false
+}
+
+// NewWriterPolicy returns a new writer writing to <dst> and handling
+// overflows with <policy>.
+func NewWriterPolicy(dst []byte, policy OverflowPolicy) Writer {
+	w := NewWriter(dst)
+	w.policy = policy
+	if policy != OverflowIgnore {
+		// every write checks for overflows on the slow path
+		w.room = 0
+	}
+	return w
+}
+
+// drop applies the overflow policy and returns whether a write of <bits>
+// must be dropped
+func (w *Writer) drop(bits uint) bool {
+	if w.CanWrite(bits) {
+		return false
+	}
+	switch w.policy {
+	case OverflowPanic:
+		panic(ErrOverflow)
+	case OverflowSaturate:
+		w.err = ErrOverflow
+		return true
+	}
+	return false
+}
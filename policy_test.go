@@ -0,0 +1,143 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func expectPanic(t *testing.T, expected interface{}, fn func()) {
+	defer func() {
+		expect(t, expected, recover())
+	}()
+	fn()
+}
+
+func TestReaderStrict(t *testing.T) {
+	src := makeSource(7)
+	for size := 1; size <= 7; size++ {
+		for _, bits := range []uint{1, 3, 8, 13, 32} {
+			r := NewReaderStrict(src[:size])
+			for r.LeftBits() >= bits {
+				r.Uint32(bits)
+				expect(t, nil, r.Error())
+			}
+			r.Uint32(bits)
+			expect(t, ErrPadding, r.Error())
+			r.Reset()
+			expect(t, nil, r.Error())
+			r.Skip(uint(size * 8))
+			expect(t, nil, r.Error())
+			r.Bit()
+			expect(t, ErrPadding, r.Error())
+		}
+	}
+	r := NewReaderStrict(src)
+	r.Uint64(60)
+	expect(t, ErrPadding, r.Error())
+	r.Bit()
+	expect(t, ErrPadding, r.Error())
+	// reads past the padding overflow
+	r = NewReaderStrict(src)
+	r.Seek(64)
+	r.Bit()
+	expect(t, ErrOverflow, r.Error())
+	// long inputs have no padding
+	r = NewReaderStrict(makeSource(8))
+	r.Skip(60)
+	r.Uint32(8)
+	expect(t, ErrOverflow, r.Error())
+	r.Bit()
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestReaderPolicies(t *testing.T) {
+	buf := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	r := NewReaderPolicy(buf, OverflowIgnore)
+	r.Skip(36)
+	expect(t, uint32(0xF0), r.Uint32(8))
+	expect(t, uint(44), r.At())
+	expect(t, ErrOverflow, r.Error())
+
+	for _, op := range []func(r *Reader){
+		func(r *Reader) { r.Bit() },
+		func(r *Reader) { r.Uint32(5) },
+		func(r *Reader) { r.Int32(5) },
+		func(r *Reader) { r.Uint64(37) },
+		func(r *Reader) { r.Int64(37) },
+		func(r *Reader) { r.Bytes(5) },
+		func(r *Reader) { r.Skip(5) },
+	} {
+		r = NewReaderPolicy(buf, OverflowPanic)
+		expectPanic(t, nil, func() { op(&r) })
+		r.Reset()
+		r.Skip(40)
+		expectPanic(t, ErrOverflow, func() { op(&r) })
+		expect(t, uint(40), r.At())
+	}
+
+	r = NewReaderPolicy(buf, OverflowSaturate)
+	r.Skip(36)
+	expect(t, uint32(0), r.Uint32(8))
+	expect(t, uint(36), r.At())
+	expect(t, ErrOverflow, r.Error())
+	expect(t, uint32(0xF), r.Uint32(4))
+	expect(t, false, r.Bit())
+	expect(t, uint64(0), r.Uint64(33))
+	expect(t, int64(0), r.Int64(33))
+	expect(t, 0, len(r.Bytes(1)))
+	r.Skip(1)
+	expect(t, uint(40), r.At())
+	expect(t, ErrOverflow, r.Error())
+	r.Reset()
+	expect(t, nil, r.Error())
+	expect(t, uint64(0xFFFFFFFFFF), r.Uint64(40))
+	expect(t, nil, r.Error())
+	r = NewReaderOrder(buf, ByteLE, BitLSB)
+	r.policy = OverflowSaturate
+	r.Skip(36)
+	expect(t, uint32(0), r.Uint32(8))
+	expect(t, uint(36), r.At())
+}
+
+func TestWriterPolicies(t *testing.T) {
+	buf := make([]byte, 5)
+	w := NewWriterPolicy(buf, OverflowIgnore)
+	w.PutUint64(36, 0)
+	w.PutUint32(8, 0xFF)
+	expect(t, 44, w.Index())
+	expect(t, ErrOverflow, w.Flush())
+
+	for _, op := range []func(w *Writer){
+		func(w *Writer) { w.PutBit(true) },
+		func(w *Writer) { w.PutUint32(5, 0) },
+		func(w *Writer) { w.PutInt64(37, 0) },
+		func(w *Writer) { w.Write([]byte{0, 0, 0, 0, 0}) },
+	} {
+		w = NewWriterPolicy(buf, OverflowPanic)
+		expectPanic(t, nil, func() { op(&w) })
+		w.Reset()
+		w.PutUint64(40, 0)
+		expectPanic(t, ErrOverflow, func() { op(&w) })
+		expect(t, 40, w.Index())
+		expect(t, nil, w.Flush())
+	}
+
+	w = NewWriterPolicy(buf, OverflowSaturate)
+	w.PutUint64(36, 0xFFFFFFFFF)
+	w.PutUint32(8, 0xFF)
+	expect(t, 36, w.Index())
+	w.PutUint64(33, 0)
+	expect(t, 36, w.Index())
+	n, err := w.Write([]byte{0})
+	expect(t, 0, n)
+	expect(t, ErrOverflow, err)
+	w.PutUint32(4, 0x0)
+	expect(t, ErrOverflow, w.Flush())
+	compare(t, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xF0}, buf)
+	w.Reset()
+	w.PutUint64(40, 0)
+	expect(t, nil, w.Flush())
+}
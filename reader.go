@@ -41,41 +41,90 @@ package iobit
 
 import (
 	"encoding/binary"
+	"hash/crc32"
+	"strconv"
+	"unsafe"
 )
 
 // Reader wraps a raw byte array and provides multiple methods to read and
 // skip data bit-by-bit.
 // Its methods don't return the usual error as it is too expensive.
-// Instead, read errors can be checked with the Check() method
+// Instead, read errors can be checked with the Error() method
 type Reader struct {
-	src  []byte
-	idx  uint
-	max  uint
-	size uint
+	src    []byte
+	idx    uint
+	start  uint
+	max    uint
+	end    uint
+	fast   uint // plain reads below this position take the fast path
+	padded bool
+	order  uint8
+	policy OverflowPolicy
+	code   uint
+	table  *crc32.Table
+	crc    uint32
+	crcIdx uint
+	bitCRC BitCRC
+	err    error
 }
 
 // NewReader returns a new reader reading from <src> byte array.
 func NewReader(src []byte) Reader {
 	if len(src) >= 8 {
+		max := uint(len(src) - 8)
 		return Reader{
 			src:  src,
-			max:  uint(len(src) - 8),
-			size: uint(len(src)),
+			max:  max,
+			end:  uint(len(src)) << 3,
+			fast: (max + 1) << 3,
 		}
 	}
 	clone := make([]byte, 8)
 	copy(clone, src)
 	return Reader{
-		src:  clone,
-		size: uint(len(src)),
+		src:    clone,
+		end:    uint(len(src)) << 3,
+		fast:   8,
+		padded: true,
 	}
 }
 
-func min(a, b uint) uint {
-	if a > b {
-		return b
+// NewReaderString returns a new reader reading from <src> string.
+// Inputs of at least 8 bytes are read in place without any copy, so
+// slices returned by Bytes & LeftBytes alias the string memory and must
+// never be modified. Shorter inputs are copied like in NewReader.
+func NewReaderString(src string) Reader {
+	if len(src) < 8 {
+		return NewReader([]byte(src))
 	}
-	return a
+	return NewReader(unsafe.Slice(unsafe.StringData(src), len(src)))
+}
+
+// NewReaderAt returns a new reader reading from <src> starting at bit
+// <start>.
+// Positions, left bits & overflows are still relative to the whole <src>,
+// and Reset moves back to <start>.
+func NewReaderAt(src []byte, start uint) Reader {
+	r := NewReader(src)
+	r.idx = start
+	r.start = start
+	return r
+}
+
+// NewReaderLimit returns a new reader reading at most <bits> bits from
+// <src>, as if it ended there: reads past the limit return zeros & flag
+// an overflow.
+func NewReaderLimit(src []byte, bits uint) Reader {
+	r := NewReader(src)
+	r.end = min(r.end, bits)
+	r.clampFast()
+	return r
+}
+
+// clampFast keeps fast reads of up to 32 bits within the reader end, so
+// that reads past a limit take the slow path & return zeros.
+func (r *Reader) clampFast() {
+	r.fast = min(r.fast, r.end-min(r.end, 31))
 }
 
 func bswap16(v uint16) uint16 {
@@ -88,31 +137,128 @@ func bswap32(val uint32) uint32 {
 
 // Bit reads the next bit as a boolean.
 func (r *Reader) Bit() bool {
+	if r.idx >= r.fast {
+		return slowBit(r, (*Reader).bitSlow)
+	}
+	val := r.src[r.idx>>3] << (r.idx & 7) >> 7
+	r.idx++
+	return val != 0
+}
+
+// bitSlow is Bit for positions past fast, ordered readers & policies
+func (r *Reader) bitSlow() bool {
+	if r.policy != OverflowIgnore && r.drop(1) {
+		return false
+	}
 	skip := min(r.idx>>3, r.max+7)
 	val := r.src[skip]
-	val <<= r.idx - skip<<3
-	val >>= 7
+	if r.order&orderBitLSB != 0 {
+		val >>= r.idx - skip<<3
+		val <<= 7
+	} else {
+		val <<= r.idx - skip<<3
+		val >>= 7
+	}
 	r.idx++
-	return val != 0
+	return val != 0 && r.idx <= r.end
 }
 
+// get64 returns the 64 bits window at the reader position & skips <bits>.
+// The position must be below fast.
 func (r *Reader) get64(bits uint) uint64 {
+	val := binary.BigEndian.Uint64(r.src[r.idx>>3:]) << (r.idx & 7)
+	r.idx += bits
+	return val
+}
+
+// getSlow64 is get64 for any position
+func (r *Reader) getSlow64(bits uint) uint64 {
 	skip := min(r.idx>>5<<2, r.max)
 	val := binary.BigEndian.Uint64(r.src[skip:])
+	// past the buffer, the shift is 64 or more & clears val
 	val <<= r.idx - skip<<3
+	// bits past a shorter end are cleared too
+	if left := r.end - min(r.idx, r.end); left < 64 {
+		val &= ^(^uint64(0) >> left)
+	}
 	r.idx += bits
 	return val
 }
 
 func (r *Reader) read32(bits uint) uint64 {
+	if r.idx >= r.fast {
+		return slowRead(r, bits, (*Reader).readSlow)
+	}
 	return r.get64(bits) >> (64 - bits)
 }
 
 func (r *Reader) read32i(bits uint) int64 {
+	// zero-width reads would sign-extend the next bit
+	if r.idx >= r.fast || bits == 0 {
+		return slowReadi(r, bits, (*Reader).readSlowi)
+	}
 	// we need sign extension
 	return int64(r.get64(bits)) >> (64 - bits)
 }
 
+// readSlow is read32 for positions past fast, ordered readers & policies
+func (r *Reader) readSlow(bits uint) uint64 {
+	if r.order != 0 {
+		return r.readOrdered(bits)
+	}
+	if r.policy != OverflowIgnore && r.drop(bits) {
+		return 0
+	}
+	return r.getSlow64(bits) >> (64 - bits)
+}
+
+// readSlowi is read32i for positions past fast, ordered readers & policies
+func (r *Reader) readSlowi(bits uint) int64 {
+	if bits == 0 {
+		return 0
+	}
+	if r.order != 0 {
+		return int64(r.readOrdered(bits)<<(64-bits)) >> (64 - bits)
+	}
+	if r.policy != OverflowIgnore && r.drop(bits) {
+		return 0
+	}
+	return int64(r.getSlow64(bits)) >> (64 - bits)
+}
+
+// Slow paths are called through the following helpers: the inliner
+// charges little for calls to a function parameter, unlike direct calls,
+// which keeps fast paths within its budget. Once inlined, they compile
+// to regular calls.
+
+func slowBit(r *Reader, bit func(*Reader) bool) bool {
+	return bit(r)
+}
+
+func slowRead(r *Reader, bits uint, read func(*Reader, uint) uint64) uint64 {
+	return read(r, bits)
+}
+
+func slowReadi(r *Reader, bits uint, read func(*Reader, uint) int64) int64 {
+	return read(r, bits)
+}
+
+func slowBytes(r *Reader, size int, bytes func(*Reader, int) []byte) []byte {
+	return bytes(r, size)
+}
+
+// RawBits reads up to 32 bits and returns the unmasked 64-bit window
+// they were extracted from, along with the number of valid bits it holds.
+// The window is left-aligned on the position before the read: its most
+// significant bit is the first bit read, so the field is value>>(64-bits)
+// and the following bits contain upcoming data. Only the first <valid>
+// bits come from the input, the remaining bits are zero.
+func (r *Reader) RawBits(bits uint) (value uint64, valid uint) {
+	skip := min(r.idx>>5<<2, r.max)
+	valid = min(64-min(r.idx-skip<<3, 64), r.LeftBits())
+	return r.getSlow64(bits), valid
+}
+
 // Byte reads one byte.
 func (r *Reader) Byte() uint8 {
 	return uint8(r.read32(8))
@@ -128,6 +274,12 @@ func (r *Reader) Int8(bits uint) int8 {
 	return int8(r.read32i(bits))
 }
 
+// Int8Le reads up to 8 signed bits in little-endian order.
+// A single byte has no byte order so it is the same as Int8.
+func (r *Reader) Int8Le(bits uint) int8 {
+	return r.Int8(bits)
+}
+
 // Be16 reads 16 unsigned bits in big-endian order.
 func (r *Reader) Be16() uint16 {
 	return uint16(r.read32(16))
@@ -143,9 +295,20 @@ func (r *Reader) Int16(bits uint) int16 {
 	return int16(r.read32i(bits))
 }
 
+// Int16Le reads up to 16 signed bits in little-endian order: the first 8
+// bits are the low byte & the remaining ones, sign-extended, the high byte.
+func (r *Reader) Int16Le(bits uint) int16 {
+	if bits <= 8 {
+		return r.Int16(bits)
+	}
+	lo := r.read32(8)
+	return int16(r.read32i(bits-8)<<8 | int64(lo))
+}
+
 // Le16 reads 16 unsigned bits in litle-endian order.
 func (r *Reader) Le16() uint16 {
-	return bswap16(r.Be16())
+	// swaps bytes with a single multiply to stay inlinable
+	return uint16(r.read32(16) * 0x10001 >> 8)
 }
 
 // Be32 reads 32 unsigned bits in big-endian order.
@@ -158,6 +321,23 @@ func (r *Reader) Uint32(bits uint) uint32 {
 	return uint32(r.read32(bits))
 }
 
+// ConstantTimeUint32 reads up to 32 unsigned bits in big-endian order
+// without any branch.
+// Regular reads never branch on the data itself, only on the reader
+// position when clamping the final 64-bit load. This variant clamps with
+// masks instead, so its timing depends on neither the data nor the
+// position, which suits parsing of secret material.
+func (r *Reader) ConstantTimeUint32(bits uint) uint32 {
+	skip := r.idx >> 5 << 2
+	// all ones when skip > max
+	mask := uint(int(r.max-skip) >> (strconv.IntSize - 1))
+	skip ^= (skip ^ r.max) & mask
+	val := binary.BigEndian.Uint64(r.src[skip:])
+	val <<= r.idx - skip<<3
+	r.idx += bits
+	return uint32(val >> (64 - bits))
+}
+
 // Int32 reads up to 32 signed bits in big-endian order.
 func (r *Reader) Int32(bits uint) int32 {
 	return int32(r.read32i(bits))
@@ -184,6 +364,13 @@ func (r *Reader) Le64() uint64 {
 // Uint64 reads up to 64 unsigned bits in big-endian order.
 func (r *Reader) Uint64(bits uint) uint64 {
 	var val uint64
+	if r.policy != OverflowIgnore && r.drop(bits) {
+		return 0
+	}
+	if bits > 32 && r.order&orderByteLE != 0 {
+		val = r.read32(32)
+		return val | r.read32(bits-32)<<32
+	}
 	if bits > 32 {
 		val = r.read32(32)
 		bits -= 32
@@ -197,14 +384,44 @@ func (r *Reader) Int64(bits uint) int64 {
 	if bits <= 32 {
 		return r.read32i(bits)
 	}
+	if r.policy != OverflowIgnore && r.drop(bits) {
+		return 0
+	}
+	if r.order&orderByteLE != 0 {
+		val := int64(r.read32(32))
+		return val | r.read32i(bits-32)<<32
+	}
 	val := r.read32i(32)
 	bits -= 32
 	return val<<bits | int64(r.read32(bits))
 }
 
+// Uint128Array reads up to 128 unsigned bits in big-endian order as
+// {high, low} 64-bit halves, without allocating.
+func (r *Reader) Uint128Array(bits uint) [2]uint64 {
+	if bits <= 64 {
+		return [2]uint64{0, r.Uint64(bits)}
+	}
+	hi := r.Uint64(bits - 64)
+	return [2]uint64{hi, r.Uint64(64)}
+}
+
 // Bytes returns a byte-array of input size.
 func (r *Reader) Bytes(size int) []byte {
+	if r.idx+uint(size*8) > r.end {
+		return slowBytes(r, size, (*Reader).bytesSlow)
+	}
+	skip := r.idx >> 3
+	r.idx += uint(size * 8)
+	return r.src[skip : skip+uint(size)]
+}
+
+// bytesSlow is Bytes for reads past the end
+func (r *Reader) bytesSlow(size int) []byte {
 	d := r.LeftBytes()
+	if r.policy != OverflowIgnore && r.drop(uint(size*8)) {
+		return d[:0]
+	}
 	if size > len(d) {
 		size = len(d)
 	}
@@ -217,6 +434,41 @@ func (r *Reader) String(size int) string {
 	return string(r.Bytes(size))
 }
 
+// ReadGroups reads <n> groups of <k> bits, up to 8, one per byte, as
+// unpacked by base32 (k = 5) or base64 (k = 6) style encodings.
+func (r *Reader) ReadGroups(k uint, n int) []byte {
+	groups := make([]byte, n)
+	for i := range groups {
+		groups[i] = r.Uint8(k)
+	}
+	return groups
+}
+
+// Slice returns a new reader over the next <size> bytes and skips them.
+// The reader is expected to be byte-aligned. The returned reader is
+// truncated if fewer bytes are left, in which case Error reports an
+// overflow.
+// The returned reader shares the reader orders & overflow policy.
+func (r *Reader) Slice(size int) Reader {
+	d := r.LeftBytes()
+	sub := NewReader(d[:min(uint(size), uint(len(d)))])
+	sub.order = r.order
+	sub.policy = r.policy
+	if sub.order != 0 || sub.policy != OverflowIgnore {
+		sub.fast = 0
+	}
+	r.Skip(uint(size) * 8)
+	return sub
+}
+
+// DescriptorLoop reads a byte length of <lengthBits> bits and returns a
+// reader bounded to the following loop, as found in MPEG-TS & DVB tables.
+// The reader skips the whole loop, which can be iterated until the
+// returned reader has no more bits left.
+func (r *Reader) DescriptorLoop(lengthBits uint) Reader {
+	return r.Slice(int(r.Uint32(lengthBits)))
+}
+
 // Peek returns a reader copy.
 // Useful to read data without advancing the original reader.
 func (r *Reader) Peek() *Reader {
@@ -224,11 +476,76 @@ func (r *Reader) Peek() *Reader {
 	return &p
 }
 
+// PeekBits returns up to 64 upcoming bits in big-endian order without
+// moving the reader.
+// Bits past the end read as zeros whatever the overflow policy, & no
+// error is flagged.
+func (r *Reader) PeekBits(bits uint) uint64 {
+	p := *r
+	p.policy = OverflowIgnore
+	return p.Uint64(bits)
+}
+
 // Skip skips n bits.
 func (r *Reader) Skip(bits uint) {
+	if r.policy != OverflowIgnore && r.drop(bits) {
+		return
+	}
 	r.idx += bits
 }
 
+// Align skips up to the next byte boundary and returns the number of
+// skipped bits.
+// Aligning past the end flags an overflow like Skip.
+func (r *Reader) Align() uint {
+	start := r.idx
+	r.Skip(-r.idx & 7)
+	return r.idx - start
+}
+
+// Seek moves the reader to absolute position <bit>, backward or forward.
+// Seeking past the end is reported by Error.
+func (r *Reader) Seek(bit uint) {
+	r.idx = bit
+}
+
+// SeekRelative moves the reader <delta> bits forward, or backward when
+// negative.
+// Moving forward acts like Skip while moving backward stops at bit 0.
+func (r *Reader) SeekRelative(delta int) {
+	if delta >= 0 {
+		r.Skip(uint(delta))
+		return
+	}
+	r.idx -= min(uint(-delta), r.idx)
+}
+
+// Reserved skips n reserved bits.
+// Returns ErrReserved if any of them is not zero.
+// The reader advances even on error.
+func (r *Reader) Reserved(bits uint) error {
+	return r.reserved(bits, 0)
+}
+
+// ReservedOnes skips n reserved bits.
+// Returns ErrReserved if any of them is not one.
+// The reader advances even on error.
+func (r *Reader) ReservedOnes(bits uint) error {
+	return r.reserved(bits, ^uint32(0))
+}
+
+func (r *Reader) reserved(bits uint, fill uint32) error {
+	var err error
+	for bits > 0 {
+		n := min(bits, 32)
+		if r.Uint32(n) != fill>>(32-n) {
+			err = ErrReserved
+		}
+		bits -= n
+	}
+	return err
+}
+
 // At returns the current reader position in bits.
 func (r *Reader) At() uint {
 	return r.idx
@@ -236,28 +553,256 @@ func (r *Reader) At() uint {
 
 // LeftBits returns the number of bits left to read.
 func (r *Reader) LeftBits() uint {
-	return r.size<<3 - min(r.idx, r.size<<3)
+	return r.end - min(r.idx, r.end)
+}
+
+// More returns whether at least one bit is left to read.
+// It is false as soon as the reader has overflowed.
+func (r *Reader) More() bool {
+	return r.LeftBits() > 0 && r.Error() == nil
+}
+
+// Padded returns whether the reader works on a zero-padded copy of its
+// input, which happens when it is shorter than 8 bytes.
+// Reads may then return padding bits before Error reports an overflow at
+// the true input size.
+func (r *Reader) Padded() bool {
+	return r.padded
 }
 
 // LeftBytes returns a slice of the contents of the unread reader portion.
 // Note that this slice is byte aligned even if the reader is not.
 func (r *Reader) LeftBytes() []byte {
 	skip := r.idx >> 3
-	if skip >= r.size {
+	top := r.end >> 3
+	if skip >= top {
 		return r.src[:0]
 	}
-	return r.src[skip:r.size]
+	return r.src[skip:top]
+}
+
+// Source returns the slice backing the reader, cut at its logical end, &
+// the current bit offset into it, so that other decoders can take over
+// without any copy.
+// Inputs shorter than 8 bytes are read from a zero-padded copy, see Padded,
+// in which case the returned slice is that copy & not the original input.
+func (r *Reader) Source() (src []byte, bitOffset uint) {
+	return r.src[:(r.end+7)>>3], r.idx
+}
+
+// RegionsEqual returns whether the <bits> bits at absolute positions <offA>
+// & <offB> are identical, without moving the reader.
+// Regions past the reader end are never equal.
+func (r *Reader) RegionsEqual(offA, offB, bits uint) bool {
+	if max(offA, offB)+bits > r.end {
+		return false
+	}
+	a, b := *r, *r
+	a.idx, b.idx = offA, offB
+	a.policy, b.policy = OverflowIgnore, OverflowIgnore
+	for bits > 0 {
+		n := min(bits, 64)
+		if a.Uint64(n) != b.Uint64(n) {
+			return false
+		}
+		bits -= n
+	}
+	return true
+}
+
+// Measure runs <fn> on the reader and returns the number of bits it
+// consumed.
+func (r *Reader) Measure(fn func(*Reader)) uint {
+	start := r.idx
+	fn(r)
+	return r.idx - start
+}
+
+// Limit caps the reader <bits> ahead of its current position so that
+// reads past the cap return zeros & flag an overflow.
+// Calling release restores the previous limit and moves the reader to the
+// end of the capped region, skipping whatever was left unread. Reads past
+// the region leave the reader with a sticky ErrOverflow.
+func (r *Reader) Limit(bits uint) (release func()) {
+	end, fast := r.end, r.fast
+	limit := r.idx + bits
+	r.end = min(limit, end)
+	r.clampFast()
+	return func() {
+		if r.idx > limit && r.err == nil {
+			r.err = ErrOverflow
+		}
+		r.end = end
+		r.fast = fast
+		r.idx = limit
+	}
+}
+
+// SetMaxCodeLength caps every variable-length code read by the reader to
+// <bits> bits, zero removing the cap.
+// Decoders flag ErrTooLong & stop as soon as a code exceeds the cap, which
+// bounds the work spent on adversarial input.
+func (r *Reader) SetMaxCodeLength(bits uint) {
+	r.code = bits
+}
+
+// codeTooLong flags ErrTooLong & returns true if the code started at
+// <start> exceeds the maximum code length.
+func (r *Reader) codeTooLong(start uint) bool {
+	if r.code == 0 || r.idx-start <= r.code {
+		return false
+	}
+	r.err = ErrTooLong
+	return true
+}
+
+// codeTruncated flags ErrTruncated & returns true if the current code ran
+// past the reader end.
+func (r *Reader) codeTruncated() bool {
+	if r.idx <= r.end && r.err != ErrOverflow {
+		return false
+	}
+	r.err = ErrTruncated
+	return true
+}
+
+// EnableCRC starts a running CRC-32 computed with <table> over every
+// complete byte consumed from now on, see CRC.
+func (r *Reader) EnableCRC(table *crc32.Table) {
+	r.table = table
+	r.crc = 0
+	r.crcIdx = (r.idx + 7) >> 3
+}
+
+// CRC returns the running CRC-32 enabled by EnableCRC, & zero otherwise.
+// It covers every byte entirely consumed since the first byte boundary
+// following EnableCRC, once each even when moving backward, whether
+// those bytes were read in fields of any size or skipped.
+func (r *Reader) CRC() uint32 {
+	top := min(r.idx, r.end) >> 3
+	if r.table != nil && top > r.crcIdx {
+		r.crc = crc32.Update(r.crc, r.table, r.src[r.crcIdx:top])
+		r.crcIdx = top
+	}
+	return r.crc
 }
 
 // Reset resets the reader to its initial position.
 func (r *Reader) Reset() {
-	r.idx = 0
+	r.idx = r.start
+	r.err = nil
 }
 
 // Error returns whether the reader encountered an error.
 func (r *Reader) Error() error {
-	if r.idx > r.size<<3 {
+	if r.err != nil {
+		return r.err
+	}
+	if r.idx > r.end {
 		return ErrOverflow
 	}
 	return nil
 }
+
+// Check returns whether the reader encountered an error.
+// It is an alias of Error kept for compatibility.
+func (r *Reader) Check() error {
+	return r.Error()
+}
+
+// Flag reads a presence bit.
+// It is an alias of Bit meant to document optional fields.
+func (r *Reader) Flag() bool {
+	return r.Bit()
+}
+
+// Optional reads up to 32 bits only if <present> is set, usually from a
+// previous Flag call.
+// Returns zero when not present, and echoes <present>.
+func (r *Reader) Optional(present bool, bits uint) (uint32, bool) {
+	if !present {
+		return 0, false
+	}
+	return r.Uint32(bits), true
+}
+
+// ReadXor reads up to 32 bits and xors them with the low bits of <key>.
+func (r *Reader) ReadXor(bits uint, key uint64) uint32 {
+	return r.Uint32(bits) ^ uint32(key&(1<<bits-1))
+}
+
+// RLE reads a run made of a <countBits> count followed by a <valueBits>
+// value, each up to 32 bits.
+func (r *Reader) RLE(countBits, valueBits uint) (count uint32, value uint32) {
+	count = r.Uint32(countBits)
+	value = r.Uint32(valueBits)
+	return
+}
+
+// ReadFrame reads one frame of <channels> interleaved signed samples of
+// up to 32 bits each.
+func (r *Reader) ReadFrame(channels int, bits uint) []int32 {
+	frame := make([]int32, channels)
+	for i := range frame {
+		frame[i] = r.Int32(bits)
+	}
+	return frame
+}
+
+// NamedField describes a field read by ReadNamed.
+type NamedField struct {
+	Name   string
+	Bits   uint
+	Signed bool
+}
+
+// ReadNamed reads each field in order and returns them indexed by name.
+// Signed fields are sign-extended and stored as their two's-complement
+// uint64, so int64(v) recovers the value.
+func (r *Reader) ReadNamed(fields []NamedField) map[string]uint64 {
+	values := make(map[string]uint64, len(fields))
+	for _, f := range fields {
+		if f.Signed {
+			values[f.Name] = uint64(r.Int64(f.Bits))
+		} else {
+			values[f.Name] = r.Uint64(f.Bits)
+		}
+	}
+	return values
+}
+
+// BitAccumulator builds a value piecewise from successive reads.
+// Useful when a field is split by interleaved flags.
+type BitAccumulator struct {
+	val  uint64
+	bits uint
+}
+
+// Shift shifts the accumulated value left and appends up to 64 bits read
+// from <r>.
+func (a *BitAccumulator) Shift(r *Reader, bits uint) {
+	if bits >= 64 {
+		a.val = 0
+	} else {
+		a.val <<= bits
+	}
+	a.val |= r.Uint64(bits)
+	a.bits += bits
+}
+
+// Bits returns the total number of bits accumulated.
+func (a *BitAccumulator) Bits() uint {
+	return a.bits
+}
+
+// Uint64 returns the accumulated value.
+// Only the last 64 accumulated bits are kept.
+func (a *BitAccumulator) Uint64() uint64 {
+	return a.val
+}
+
+// Reset clears the accumulator.
+func (a *BitAccumulator) Reset() {
+	a.val = 0
+	a.bits = 0
+}
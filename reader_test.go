@@ -5,6 +5,8 @@
 package iobit
 
 import (
+	"bytes"
+	"hash/crc32"
 	"testing"
 )
 
@@ -49,6 +51,102 @@ func TestBitReads(t *testing.T)       { testReads(t, bitLoop) }
 func TestBigUint64Reads(t *testing.T) { testReads(t, bigUint64Loop) }
 func TestBigInt64Reads(t *testing.T)  { testReads(t, bigInt64Loop) }
 
+func refBits(src []byte, idx, bits uint) uint64 {
+	var val uint64
+	for i := idx; i < idx+bits; i++ {
+		val = val<<1 | uint64(src[i>>3]>>(7-i&7)&1)
+	}
+	return val
+}
+
+func TestUint64Boundaries(t *testing.T) {
+	for _, pattern := range []byte{0xFF, 0xAA, 0x55, 0x00, 0x81} {
+		src := make([]byte, 24)
+		for i := range src {
+			src[i] = pattern
+		}
+		copy(src[16:], makeSource(8))
+		// start reads around each 32-bit fetch boundary
+		for base := uint(0); base <= 96; base += 32 {
+			for idx := base - min(base, 8); idx <= base+8; idx++ {
+				for bits := uint(33); bits <= 64; bits++ {
+					if idx+bits > uint(len(src)*8) {
+						continue
+					}
+					r := NewReader(src)
+					r.Skip(idx)
+					ref := refBits(src, idx, bits)
+					expect(t, ref, r.Peek().Uint64(bits))
+					expect(t, int64(ref<<(64-bits))>>(64-bits), r.Int64(bits))
+					expect(t, idx+bits, r.At())
+				}
+			}
+		}
+	}
+}
+
+func TestUint128Array(t *testing.T) {
+	src := makeSource(40)
+	for idx := uint(0); idx <= 64; idx += 7 {
+		for bits := uint(65); bits <= 128; bits++ {
+			r := NewReader(src)
+			r.Skip(idx)
+			ref := [2]uint64{
+				refBits(src, idx, bits-64),
+				refBits(src, idx+bits-64, 64),
+			}
+			v := r.Uint128Array(bits)
+			expect(t, ref, v)
+			expect(t, idx+bits, r.At())
+			dst := make([]byte, len(src))
+			w := NewWriter(dst)
+			w.PutUint64(idx, refBits(src, 0, idx))
+			w.PutUint128Array(bits, v)
+			expect(t, idx+bits, uint(w.Index()))
+			w.PutUint32(uint(-w.Index()&7), 0)
+			expect(t, nil, w.Flush())
+			r = NewReader(dst)
+			r.Skip(idx)
+			expect(t, v, r.Uint128Array(bits))
+		}
+	}
+	r := NewReader(src)
+	expect(t, [2]uint64{0, refBits(src, 0, 40)}, r.Uint128Array(40))
+}
+
+func TestRawBits(t *testing.T) {
+	src := makeSource(16)
+	for idx := uint(0); idx <= 130; idx++ {
+		for _, bits := range []uint{0, 1, 7, 32} {
+			r := NewReader(src)
+			r.Skip(idx)
+			value, valid := r.RawBits(bits)
+			expect(t, idx+bits, r.At())
+			left := uint(len(src)*8) - min(idx, uint(len(src)*8))
+			if left >= 33 && valid < 33 {
+				t.Fatal("window too small", idx, valid)
+			}
+			expect(t, true, valid <= left)
+			expect(t, refBits(src, idx, valid), value>>(64-valid))
+			expect(t, uint64(0), value<<valid)
+		}
+	}
+}
+
+func TestConstantTimeUint32(t *testing.T) {
+	for _, size := range []int{0, 3, 8, 13, 64} {
+		src := makeSource(size)
+		for idx := uint(0); idx < uint(size*8)+80; idx++ {
+			for _, bits := range []uint{0, 1, 5, 17, 32} {
+				r := NewReader(src)
+				r.Skip(idx)
+				expect(t, r.Peek().Uint32(bits), r.ConstantTimeUint32(bits))
+				expect(t, idx+bits, r.At())
+			}
+		}
+	}
+}
+
 func TestSigned(t *testing.T) {
 	big := []byte{0x7E}
 	r := NewReader(big)
@@ -62,6 +160,15 @@ func TestSigned(t *testing.T) {
 	expect(t, int64(-1), r.Int64(1))
 	expect(t, int64(-1), r.Int64(33))
 	expect(t, int64(0), r.Int64(5))
+	// zero-width reads before a set bit, on fast & slow paths
+	for _, size := range []int{1, 64} {
+		r = NewReader(bytes.Repeat([]byte{0xFF}, size))
+		expect(t, int32(0), r.Int32(0))
+		expect(t, int64(0), r.Int64(0))
+		r.Skip(uint(size*8 - 4))
+		expect(t, int32(0), r.Int32(0))
+		expect(t, uint(size*8-4), r.At())
+	}
 }
 
 func TestReadHelpers(t *testing.T) {
@@ -71,6 +178,7 @@ func TestReadHelpers(t *testing.T) {
 	r.Skip(1)
 	expect(t, uint(1), r.At())
 	expect(t, uint(7), r.LeftBits())
+	expect(t, true, r.More())
 	for i := 0; i < 8; i++ {
 		p := r.Peek()
 		expect(t, true, p.Bit())
@@ -85,6 +193,7 @@ func TestReadHelpers(t *testing.T) {
 	expect(t, uint(0), r.LeftBits())
 	expect(t, 0, len(r.LeftBytes()))
 	expect(t, nil, r.Error())
+	expect(t, false, r.More())
 	r.Skip(1)
 	expect(t, uint(9), r.At())
 	expect(t, uint(0), r.LeftBits())
@@ -113,6 +222,59 @@ func TestReadHelpers(t *testing.T) {
 	expect(t, int16(r.Peek().Int32(15)), r.Int16(15))
 }
 
+func TestReaderString(t *testing.T) {
+	for _, src := range []string{"", "a", "abcdefg", "abcdefgh", "abcdefghijklmnop"} {
+		r := NewReaderString(src)
+		expect(t, uint(len(src)*8), r.LeftBits())
+		expect(t, src, r.Peek().String(len(src)))
+		for i := 0; i < len(src); i++ {
+			expect(t, src[i], r.Byte())
+		}
+		expect(t, nil, r.Error())
+		r.Skip(1)
+		expect(t, ErrOverflow, r.Error())
+	}
+}
+
+func TestReadsPastEnd(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 8, 9, 16, 33} {
+		src := make([]byte, size)
+		for i := range src {
+			src[i] = 0xFF
+		}
+		end := uint(size * 8)
+		for _, skip := range []uint{0, 1, 31, 32, 63, 64, 65, 1000, 1 << 20} {
+			r := NewReader(src)
+			r.Skip(end + skip)
+			expect(t, false, r.Bit())
+			expect(t, uint32(0), r.Peek().Uint32(32))
+			expect(t, int32(0), r.Peek().Int32(32))
+			expect(t, uint64(0), r.Peek().Uint64(64))
+			expect(t, int64(0), r.Peek().Int64(64))
+			expect(t, uint64(0), r.Peek().Be64())
+			expect(t, 0, len(r.Peek().Bytes(4)))
+			expect(t, ErrOverflow, r.Error())
+			expect(t, uint(0), r.LeftBits())
+		}
+	}
+}
+
+func TestPadded(t *testing.T) {
+	for size := 0; size < 10; size++ {
+		src := make([]byte, size)
+		for i := range src {
+			src[i] = 0xFF
+		}
+		r := NewReader(src)
+		expect(t, size < 8, r.Padded())
+		expect(t, uint(size*8), r.LeftBits())
+		r.Skip(uint(size * 8))
+		expect(t, nil, r.Error())
+		expect(t, uint32(0), r.Uint32(1))
+		expect(t, ErrOverflow, r.Error())
+	}
+}
+
 func TestBadSliceRead(t *testing.T) {
 	buf := []byte{0x01, 0x02, 0x03}
 	r := NewReader(buf[:])
@@ -124,6 +286,515 @@ func TestBadSliceRead(t *testing.T) {
 	expect(t, 0, len(r.LeftBytes()))
 }
 
+func TestReserved(t *testing.T) {
+	buf := []byte{0x00, 0x0F, 0xFF, 0xFF, 0xFF, 0xFF, 0x7E}
+	r := NewReader(buf)
+	expect(t, nil, r.Reserved(12))
+	expect(t, nil, r.ReservedOnes(35))
+	expect(t, uint(47), r.At())
+	expect(t, ErrReserved, r.ReservedOnes(2))
+	expect(t, uint(49), r.At())
+	r.Reset()
+	expect(t, ErrReserved, r.Reserved(13))
+	expect(t, uint(13), r.At())
+	expect(t, ErrReserved, r.ReservedOnes(40))
+	expect(t, nil, r.Reserved(0))
+	expect(t, nil, r.Error())
+}
+
+func TestBitAccumulator(t *testing.T) {
+	// value 0x2B5 split in 3, 4 & 3 bits by two flags
+	buf := []byte{0xB6, 0x50}
+	r := NewReader(buf)
+	var a BitAccumulator
+	a.Shift(&r, 3)
+	expect(t, true, r.Bit())
+	a.Shift(&r, 4)
+	expect(t, false, r.Bit())
+	a.Shift(&r, 3)
+	expect(t, uint(10), a.Bits())
+	expect(t, uint64(0x2B5), a.Uint64())
+	a.Reset()
+	r.Reset()
+	a.Shift(&r, 0)
+	a.Shift(&r, 16)
+	expect(t, uint64(0xB650), a.Uint64())
+	expect(t, uint(16), a.Bits())
+}
+
+func TestOptional(t *testing.T) {
+	buf := []byte{0xD3, 0x60}
+	r := NewReader(buf)
+	v, ok := r.Optional(r.Flag(), 4)
+	expect(t, uint32(0xA), v)
+	expect(t, true, ok)
+	v, ok = r.Optional(r.Flag(), 4)
+	expect(t, uint32(0), v)
+	expect(t, false, ok)
+	v, ok = r.Optional(r.Flag(), 5)
+	expect(t, uint32(0x16), v)
+	expect(t, true, ok)
+	expect(t, uint(12), r.At())
+}
+
+func TestReaderAt(t *testing.T) {
+	buf := []byte{0x12, 0x34, 0x56}
+	r := NewReaderAt(buf, 12)
+	expect(t, uint(12), r.At())
+	expect(t, uint(12), r.LeftBits())
+	expect(t, uint16(0x456), r.Uint16(12))
+	expect(t, nil, r.Error())
+	r.Reset()
+	expect(t, uint(12), r.At())
+	r = NewReaderAt(buf, 25)
+	expect(t, uint(0), r.LeftBits())
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestReaderLimit(t *testing.T) {
+	buf := []byte{0x12, 0x34, 0x56}
+	r := NewReaderLimit(buf, 12)
+	expect(t, uint(12), r.LeftBits())
+	expect(t, 1, len(r.LeftBytes()))
+	expect(t, uint16(0x123), r.Uint16(12))
+	expect(t, nil, r.Error())
+	expect(t, false, r.More())
+	// bits past the limit read as zeros
+	expect(t, uint8(0), r.Uint8(4))
+	expect(t, ErrOverflow, r.Error())
+	r = NewReaderLimit(buf, 12)
+	r.Skip(8)
+	expect(t, uint8(0x30), r.Uint8(8))
+	expect(t, false, r.Bit())
+	expect(t, ErrOverflow, r.Error())
+	r = NewReaderOrder(buf, ByteLE, BitLSB)
+	r.end = 12
+	r.Skip(8)
+	expect(t, uint8(0x04), r.Uint8(8))
+	r = NewReaderLimit(buf, 64)
+	expect(t, uint(24), r.LeftBits())
+	// combined with a start offset
+	r = NewReaderLimit(buf, 20)
+	r.Skip(8)
+	expect(t, uint(12), r.LeftBits())
+	expect(t, uint16(0x345), r.Uint16(12))
+	expect(t, nil, r.Error())
+}
+
+func TestSource(t *testing.T) {
+	src := makeSource(16)
+	r := NewReader(src)
+	r.Skip(13)
+	r.Uint32(7)
+	got, offset := r.Source()
+	expect(t, r.At(), offset)
+	expect(t, uint(20), offset)
+	expect(t, &src[0], &got[0])
+	expect(t, len(src), len(got))
+	r = NewReader(src[:3])
+	r.Skip(5)
+	got, offset = r.Source()
+	expect(t, uint(5), offset)
+	expect(t, src[:3], got)
+	expect(t, true, &src[0] != &got[0])
+	r = NewReaderLimit(src, 21)
+	got, _ = r.Source()
+	expect(t, 3, len(got))
+}
+
+func TestRegionsEqual(t *testing.T) {
+	src := makeSource(32)
+	// copy bits 3..163 at bit 181, the first 150 of which are left intact
+	dst := append([]byte{}, src[:20]...)
+	dst = append(dst, make([]byte, 28)...)
+	w := NewWriter(dst[20:])
+	w.PutUint32(21, 0)
+	for i := uint(0); i < 160; i += 32 {
+		w.PutBe32(uint32(refBits(src, 3+i, 32)))
+	}
+	w.PutUint32(3, 0)
+	expect(t, nil, w.Flush())
+	r := NewReader(dst)
+	r.Skip(5)
+	for _, bits := range []uint{0, 1, 63, 64, 65, 150} {
+		expect(t, true, r.RegionsEqual(3, 181, bits))
+		expect(t, true, r.RegionsEqual(181, 3, bits))
+	}
+	dst[30] ^= 0x10
+	expect(t, false, r.RegionsEqual(3, 181, 150))
+	expect(t, true, r.RegionsEqual(3, 181, 60))
+	expect(t, false, r.RegionsEqual(3, 360, 33))
+	expect(t, true, r.RegionsEqual(0, 384, 0))
+	expect(t, uint(5), r.At())
+	expect(t, nil, r.Error())
+}
+
+func TestMaxCodeLength(t *testing.T) {
+	zeros := make([]byte, 64)
+	ones := make([]byte, 64)
+	for i := range ones {
+		ones[i] = 0xFF
+	}
+	h, err := NewCanonicalHuffman([]uint8{1, 2, 4, 4, 4, 4})
+	expect(t, nil, err)
+	for _, v := range []struct {
+		name   string
+		src    []byte
+		decode func(r *Reader)
+	}{
+		{"varint", ones, func(r *Reader) { r.VarintBE() }},
+		{"midi", ones, func(r *Reader) { r.MidiVLQ() }},
+		{"expgolomb", zeros, func(r *Reader) { r.InterleavedExpGolomb() }},
+		{"huffman", ones, func(r *Reader) { h.Decode(r) }},
+	} {
+		r := NewReader(v.src)
+		r.SetMaxCodeLength(3)
+		v.decode(&r)
+		if r.Error() != ErrTooLong || r.At() > 8 {
+			t.Fatal(v.name, r.Error(), r.At())
+		}
+	}
+	// codes of exactly the maximum length are accepted
+	buf := make([]byte, 8)
+	w := NewWriter(buf)
+	w.PutVarintBE(300)
+	w.PutInterleavedExpGolomb(6)
+	w.PutUint32(3, 0)
+	expect(t, nil, w.Flush())
+	r := NewReader(buf)
+	r.SetMaxCodeLength(16)
+	expect(t, uint64(300), r.VarintBE())
+	r.SetMaxCodeLength(5)
+	expect(t, uint32(6), r.InterleavedExpGolomb())
+	expect(t, nil, r.Error())
+	r.Reset()
+	r.SetMaxCodeLength(15)
+	r.VarintBE()
+	expect(t, ErrTooLong, r.Error())
+	r.Reset()
+	r.SetMaxCodeLength(0)
+	expect(t, uint64(300), r.VarintBE())
+	r.SetMaxCodeLength(4)
+	expect(t, uint32(0), r.InterleavedExpGolomb())
+	expect(t, ErrTooLong, r.Error())
+}
+
+func TestAlign(t *testing.T) {
+	src := []byte{0xFF, 0x12, 0x34}
+	r := NewReader(src)
+	expect(t, uint(0), r.Align())
+	r.Skip(3)
+	expect(t, uint(5), r.Align())
+	expect(t, uint8(0x12), r.Byte())
+	expect(t, uint(0), r.Align())
+	r.Skip(1)
+	expect(t, uint(7), r.Align())
+	expect(t, uint(24), r.At())
+	expect(t, nil, r.Error())
+	r = NewReaderLimit(src, 20)
+	r.Skip(17)
+	expect(t, uint(7), r.Align())
+	expect(t, ErrOverflow, r.Error())
+	r = NewReaderPolicy(src, OverflowSaturate)
+	r.Limit(20)
+	r.Skip(17)
+	expect(t, uint(0), r.Align())
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestCheck(t *testing.T) {
+	r := NewReader([]byte{0x12})
+	r.Byte()
+	expect(t, nil, r.Check())
+	r.Bit()
+	expect(t, ErrOverflow, r.Check())
+	expect(t, r.Error(), r.Check())
+}
+
+func TestSignedLe(t *testing.T) {
+	r := NewReader([]byte{0x34, 0x82, 0xFE, 0xFF, 0xF0})
+	expect(t, int16(-0x7DCC), r.Int16Le(16))
+	expect(t, int16(-2), r.Int16Le(16))
+	expect(t, int8(-1), r.Int8Le(4))
+	expect(t, int8(0), r.Int8Le(4))
+	src := makeSource(64)
+	for bits := uint(1); bits <= 16; bits++ {
+		r = NewReader(src)
+		for r.LeftBits() >= bits {
+			ref := r.Peek()
+			if bits <= 8 {
+				expect(t, r.Peek().Int8(bits), r.Peek().Int8Le(bits))
+			}
+			got := r.Int16Le(bits)
+			var v uint64
+			if bits > 8 {
+				lo := ref.Uint64(8)
+				v = ref.Uint64(bits-8)<<8 | lo
+			} else {
+				v = ref.Uint64(bits)
+			}
+			expect(t, int16(int64(v<<(64-bits))>>(64-bits)), got)
+		}
+	}
+	expect(t, nil, r.Error())
+}
+
+func TestReaderCRC(t *testing.T) {
+	// PNG IEND chunk
+	r := NewReader([]byte{0, 0, 0, 0, 'I', 'E', 'N', 'D', 0xAE, 0x42, 0x60, 0x82})
+	r.Skip(32)
+	r.EnableCRC(crc32.IEEETable)
+	expect(t, "IEND", r.String(4))
+	crc := r.CRC()
+	expect(t, crc, r.Be32())
+	src := makeSource(64)
+	r = NewReader(src)
+	r.Skip(3)
+	r.EnableCRC(crc32.IEEETable)
+	for _, bits := range []uint{5, 3, 17, 32, 1, 7} {
+		for i := 0; i < 4; i++ {
+			r.Uint32(bits)
+			crc = r.CRC()
+			expect(t, crc32.ChecksumIEEE(src[1:r.At()/8]), crc)
+		}
+	}
+	// moving backward doesn't count bytes twice
+	r.Seek(8)
+	expect(t, crc, r.CRC())
+	r.Seek(512)
+	crc = r.CRC()
+	expect(t, crc32.ChecksumIEEE(src[1:]), crc)
+	r.Skip(8)
+	expect(t, crc, r.CRC())
+	r = NewReader(src)
+	r.Byte()
+	expect(t, uint32(0), r.CRC())
+}
+
+func TestPeekBits(t *testing.T) {
+	src := makeSource(16)
+	r := NewReader(src)
+	r.Skip(5)
+	for _, bits := range []uint{0, 1, 7, 32, 33, 64} {
+		expect(t, refBits(src, 5, bits), r.PeekBits(bits))
+		expect(t, uint(5), r.At())
+	}
+	r = NewReaderPolicy(src[:3], OverflowPanic)
+	r.Skip(20)
+	expect(t, uint64(src[2]&0xF)<<60, r.PeekBits(64))
+	expect(t, uint(20), r.At())
+	expect(t, nil, r.Error())
+}
+
+func TestSeek(t *testing.T) {
+	src := makeSource(8)
+	r := NewReader(src)
+	r.Seek(24)
+	expect(t, src[3], r.Byte())
+	r.Seek(4)
+	expect(t, uint8(src[0]&0xF), r.Uint8(4))
+	expect(t, src[1], r.Byte())
+	r.Seek(64)
+	expect(t, nil, r.Error())
+	r.Seek(65)
+	expect(t, ErrOverflow, r.Error())
+	r.Seek(0)
+	expect(t, nil, r.Error())
+	expect(t, src[0], r.Byte())
+}
+
+func TestSeekRelative(t *testing.T) {
+	src := makeSource(8)
+	r := NewReader(src)
+	r.SeekRelative(13)
+	expect(t, uint(13), r.At())
+	r.SeekRelative(-5)
+	expect(t, uint(8), r.At())
+	expect(t, src[1], r.Byte())
+	r.SeekRelative(0)
+	expect(t, uint(16), r.At())
+	r.SeekRelative(-100)
+	expect(t, uint(0), r.At())
+	expect(t, nil, r.Error())
+	r.SeekRelative(65)
+	expect(t, uint(65), r.At())
+	expect(t, ErrOverflow, r.Error())
+	r.SeekRelative(-1)
+	expect(t, nil, r.Error())
+	r = NewReaderPolicy(src, OverflowSaturate)
+	r.SeekRelative(60)
+	r.SeekRelative(5)
+	expect(t, uint(60), r.At())
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestTruncatedCodes(t *testing.T) {
+	h, err := NewCanonicalHuffman([]uint8{1, 2, 4, 4, 4, 4})
+	expect(t, nil, err)
+	for _, v := range []struct {
+		name   string
+		src    []byte
+		bits   uint
+		decode func(r *Reader)
+	}{
+		{"varint", []byte{0x81, 0x82}, 16, func(r *Reader) { r.VarintBE() }},
+		{"midi", []byte{0xFF}, 8, func(r *Reader) { r.MidiVLQ() }},
+		{"expgolomb", []byte{0x00, 0x00}, 16, func(r *Reader) { r.InterleavedExpGolomb() }},
+		{"expgolomb-limit", []byte{0x00, 0x01}, 15, func(r *Reader) { r.InterleavedExpGolomb() }},
+		{"huffman", []byte{0xE0}, 3, func(r *Reader) { h.Decode(r) }},
+	} {
+		for _, policy := range []OverflowPolicy{OverflowIgnore, OverflowSaturate} {
+			r := NewReaderPolicy(v.src, policy)
+			r.Limit(v.bits)
+			v.decode(&r)
+			if r.Error() != ErrTruncated {
+				t.Fatal(v.name, policy, r.Error())
+			}
+		}
+	}
+	// complete codes ending on the last bit are not truncated
+	r := NewReaderLimit([]byte{0x81, 0x02, 0x55, 0x58}, 29)
+	expect(t, uint64(130), r.VarintBE())
+	expect(t, uint32(0x7E), r.InterleavedExpGolomb())
+	expect(t, nil, r.Error())
+	// fixed-width reads still overflow
+	r.Uint32(1)
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestSlice(t *testing.T) {
+	buf := []byte{0x01, 0x02, 0x03, 0x04}
+	r := NewReader(buf)
+	r.Skip(8)
+	sub := r.Slice(2)
+	expect(t, uint(16), sub.LeftBits())
+	expect(t, uint16(0x0203), sub.Be16())
+	expect(t, uint(24), r.At())
+	sub = r.Slice(2)
+	expect(t, uint(8), sub.LeftBits())
+	expect(t, uint8(0x04), sub.Byte())
+	expect(t, ErrOverflow, r.Error())
+	// orders & policies carry over
+	r = NewReaderLSB(buf)
+	sub = r.Slice(2)
+	expect(t, uint32(0x0201), sub.Uint32(16))
+	r = NewReaderPolicy(buf, OverflowSaturate)
+	sub = r.Slice(1)
+	expect(t, uint16(0), sub.Uint16(9))
+	expect(t, uint(0), sub.At())
+	expect(t, ErrOverflow, sub.Error())
+}
+
+func TestDescriptorLoop(t *testing.T) {
+	// end of a PMT elementary stream entry
+	buf := []byte{
+		0xF0, 0x09, // reserved & es_info_length
+		0x0A, 0x04, 'e', 'n', 'g', 0x00, // ISO 639 language descriptor
+		0x52, 0x01, 0x01, // stream identifier descriptor
+		0xE1, // next entry
+	}
+	r := NewReader(buf)
+	expect(t, nil, r.ReservedOnes(4))
+	loop := r.DescriptorLoop(12)
+	expect(t, uint(8*11), r.At())
+	type descriptor struct {
+		tag  uint8
+		data string
+	}
+	var got []descriptor
+	for loop.More() {
+		tag := loop.Byte()
+		size := int(loop.Byte())
+		got = append(got, descriptor{tag, loop.String(size)})
+	}
+	expect(t, nil, loop.Error())
+	expect(t, []descriptor{{0x0A, "eng\x00"}, {0x52, "\x01"}}, got)
+	expect(t, uint8(0xE1), r.Byte())
+	expect(t, nil, r.Error())
+}
+
+func TestMeasure(t *testing.T) {
+	buf := makeSource(16)
+	r := NewReader(buf)
+	r.Skip(3)
+	bits := r.Measure(func(r *Reader) {
+		r.Uint32(7)
+		r.Bit()
+		r.Uint64(40)
+		r.Bytes(2)
+	})
+	expect(t, uint(7+1+40+16), bits)
+	expect(t, uint(0), r.Measure(func(*Reader) {}))
+}
+
+func TestLimit(t *testing.T) {
+	buf := []byte{0x12, 0x34, 0x56, 0x78}
+	r := NewReader(buf)
+	r.Skip(4)
+	// under-consumption skips the rest
+	release := r.Limit(12)
+	expect(t, uint(12), r.LeftBits())
+	expect(t, uint8(0x2), r.Uint8(4))
+	// nested limits
+	inner := r.Limit(4)
+	expect(t, uint8(0x3), r.Uint8(4))
+	expect(t, false, r.More())
+	inner()
+	expect(t, uint(4), r.LeftBits())
+	expect(t, 1, len(r.LeftBytes()))
+	release()
+	expect(t, uint(16), r.At())
+	expect(t, uint(16), r.LeftBits())
+	// over-consumption reads zeros & flags an overflow
+	release = r.Limit(4)
+	expect(t, uint8(0x50), r.Uint8(8))
+	expect(t, ErrOverflow, r.Error())
+	release()
+	expect(t, ErrOverflow, r.Error())
+	expect(t, uint(20), r.At())
+	expect(t, uint8(0x67), r.Uint8(8))
+	expect(t, ErrOverflow, r.Error())
+	r.Reset()
+	r.Skip(28)
+	expect(t, nil, r.Error())
+	// limits can't extend the reader
+	release = r.Limit(64)
+	expect(t, uint(4), r.LeftBits())
+	release()
+	expect(t, ErrOverflow, r.Error())
+	// same on the fast path of long inputs
+	r = NewReader(bytes.Repeat([]byte{0xFF}, 64))
+	r.Skip(8)
+	release = r.Limit(4)
+	expect(t, uint8(0xF0), r.Uint8(8))
+	expect(t, false, r.Bit())
+	expect(t, ErrOverflow, r.Error())
+	release()
+	expect(t, uint(12), r.At())
+	expect(t, uint32(0xF), r.Uint32(4))
+}
+
+func TestReadNamed(t *testing.T) {
+	buf := []byte{0x47, 0x7F, 0xFE, 0x10}
+	r := NewReader(buf)
+	values := r.ReadNamed([]NamedField{
+		{"sync", 8, false},
+		{"error", 1, false},
+		{"start", 1, true},
+		{"pid", 13, false},
+		{"offset", 9, true},
+	})
+	expect(t, map[string]uint64{
+		"sync":   0x47,
+		"error":  0,
+		"start":  ^uint64(0),
+		"pid":    0x1FFF,
+		"offset": 0x10,
+	}, values)
+	expect(t, int64(-1), int64(values["start"]))
+	expect(t, uint(32), r.At())
+}
+
 var Output int64
 
 type ReadBench struct {
@@ -0,0 +1,49 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// RecoverableReader tracks the last known good position of a reader so
+// that parsers can drop corrupt data & resume at the next sync pattern.
+type RecoverableReader struct {
+	r    *Reader
+	mark uint
+}
+
+// NewRecoverableReader returns a new recoverable reader on <r>, marked at
+// its current position.
+func NewRecoverableReader(r *Reader) *RecoverableReader {
+	return &RecoverableReader{r: r, mark: r.idx}
+}
+
+// Mark records the current reader position as the recovery point.
+func (rr *RecoverableReader) Mark() {
+	rr.mark = rr.r.idx
+}
+
+// ResetToMark moves the reader back to the recovery point & clears its
+// error.
+func (rr *RecoverableReader) ResetToMark() {
+	rr.r.idx = rr.mark
+	rr.r.err = nil
+}
+
+// Resync moves the reader to the first occurrence of the <bits> bits
+// <pattern>, up to 64, strictly after the recovery point, marks it & clears
+// the reader error.
+// Returns false and moves the reader to its end if there is none.
+func (rr *RecoverableReader) Resync(pattern uint64, bits uint) bool {
+	r := rr.r
+	r.err = nil
+	pattern &= 1<<bits - 1
+	for idx := rr.mark + 1; idx+bits <= r.end; idx++ {
+		r.idx = idx
+		if r.Peek().Uint64(bits) == pattern {
+			rr.mark = idx
+			return true
+		}
+	}
+	r.idx = r.end
+	return false
+}
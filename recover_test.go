@@ -0,0 +1,58 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestRecoverableReader(t *testing.T) {
+	// packets of sync byte, counter, 16-bit payload & 8-bit checksum
+	buf := make([]byte, 10*5)
+	w := NewWriter(buf)
+	for i := 0; i < 10; i++ {
+		w.PutByte(0x47)
+		w.PutByte(uint8(i))
+		w.PutBe16(uint16(i * 0x0101))
+		w.PutByte(uint8(i) ^ uint8(i*0x0101>>8) ^ uint8(i*0x0101))
+	}
+	expect(t, nil, w.Flush())
+	// corrupt packets 3 & 4, including the sync byte of packet 4
+	buf[3*5+2] ^= 0xFF
+	buf[4*5] = 0x00
+	r := NewReader(buf)
+	rr := NewRecoverableReader(&r)
+	counters := []uint8{}
+	for r.More() {
+		rr.Mark()
+		sync := r.Byte()
+		counter := r.Byte()
+		payload := r.Be16()
+		sum := r.Byte()
+		if r.Error() != nil || sync != 0x47 || sum != counter^uint8(payload>>8)^uint8(payload) {
+			rr.ResetToMark()
+			if !rr.Resync(0x47, 8) {
+				break
+			}
+			continue
+		}
+		counters = append(counters, counter)
+	}
+	expect(t, []uint8{0, 1, 2, 5, 6, 7, 8, 9}, counters)
+	expect(t, nil, r.Error())
+}
+
+func TestResync(t *testing.T) {
+	r := NewReader([]byte{0x00, 0x0B, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00})
+	rr := NewRecoverableReader(&r)
+	expect(t, true, rr.Resync(0x17, 5))
+	expect(t, uint(12), r.At())
+	expect(t, uint8(0x17), r.Uint8(5))
+	expect(t, false, rr.Resync(0x17, 5))
+	expect(t, uint(64), r.At())
+	rr.ResetToMark()
+	expect(t, uint(12), r.At())
+	expect(t, nil, r.Error())
+}
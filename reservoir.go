@@ -0,0 +1,53 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"errors"
+)
+
+// ErrReservoir happens when a frame references more reservoir bytes than
+// are available
+var ErrReservoir = errors.New("bit reservoir underflow")
+
+// ReservoirReader stitches MP3-style frames, whose main data may begin in
+// the data of previous frames, the bit reservoir.
+type ReservoirReader struct {
+	size      int
+	reservoir []byte
+	frame     []byte
+}
+
+// NewReservoirReader returns a new reservoir reader keeping the last <size>
+// bytes of frame data, 511 for MPEG-1 layer III.
+func NewReservoirReader(size int) *ReservoirReader {
+	return &ReservoirReader{size: size}
+}
+
+// Frame returns a reader over the main data of a frame, starting <begin>
+// bytes back in the reservoir, as in main_data_begin, & followed by the
+// frame <data>, which then joins the reservoir.
+// The returned reader is only valid until the next call.
+// Returns ErrReservoir if fewer than <begin> bytes are available, such as
+// right after seeking, in which case the frame still joins the reservoir.
+func (rr *ReservoirReader) Frame(begin int, data []byte) (Reader, error) {
+	var err error
+	if begin > len(rr.reservoir) || begin < 0 {
+		err = ErrReservoir
+		begin = 0
+	}
+	rr.frame = append(rr.frame[:0], rr.reservoir[len(rr.reservoir)-begin:]...)
+	rr.frame = append(rr.frame, data...)
+	rr.reservoir = append(rr.reservoir, data...)
+	if skip := len(rr.reservoir) - rr.size; skip > 0 {
+		rr.reservoir = rr.reservoir[:copy(rr.reservoir, rr.reservoir[skip:])]
+	}
+	return NewReader(rr.frame), err
+}
+
+// Reset empties the reservoir.
+func (rr *ReservoirReader) Reset() {
+	rr.reservoir = rr.reservoir[:0]
+}
@@ -0,0 +1,33 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestReservoirReader(t *testing.T) {
+	rr := NewReservoirReader(4)
+	// first frame ends with 3 bytes of main data for the second one
+	r, err := rr.Frame(0, []byte{0x12, 0x34, 0xAB, 0xCD, 0xEF})
+	expect(t, nil, err)
+	expect(t, uint16(0x1234), r.Be16())
+	r, err = rr.Frame(3, []byte{0x01, 0x02})
+	expect(t, nil, err)
+	expect(t, uint32(0xABCDEF01), r.Be32())
+	expect(t, uint8(0x02), r.Byte())
+	expect(t, uint(0), r.LeftBits())
+	expect(t, nil, r.Error())
+	// reservoir only keeps 4 bytes
+	r, err = rr.Frame(5, []byte{0x03})
+	expect(t, ErrReservoir, err)
+	expect(t, uint8(0x03), r.Byte())
+	r, err = rr.Frame(4, nil)
+	expect(t, nil, err)
+	expect(t, uint32(0xEF010203), r.Be32())
+	rr.Reset()
+	_, err = rr.Frame(1, []byte{0x04})
+	expect(t, ErrReservoir, err)
+}
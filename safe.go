@@ -0,0 +1,178 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+// SafeReader wraps a reader and checks bounds on every call, returning
+// errors immediately instead of deferring them to Error.
+// The extra check on every read makes it noticeably slower than a plain
+// reader.
+type SafeReader struct {
+	r *Reader
+}
+
+// NewSafeReader returns a new safe reader reading from <r>.
+func NewSafeReader(r *Reader) SafeReader {
+	return SafeReader{r: r}
+}
+
+// check returns ErrOverflow when reading <bits> would overflow.
+// The reader is left untouched in that case.
+func (s *SafeReader) check(bits uint) error {
+	if err := s.r.Error(); err != nil {
+		return err
+	}
+	if bits > s.r.LeftBits() {
+		return ErrOverflow
+	}
+	return nil
+}
+
+// Bit reads the next bit as a boolean.
+func (s *SafeReader) Bit() (bool, error) {
+	if err := s.check(1); err != nil {
+		return false, err
+	}
+	return s.r.Bit(), nil
+}
+
+// Byte reads one byte.
+func (s *SafeReader) Byte() (uint8, error) {
+	if err := s.check(8); err != nil {
+		return 0, err
+	}
+	return s.r.Byte(), nil
+}
+
+// Uint32 reads up to 32 unsigned bits in big-endian order.
+func (s *SafeReader) Uint32(bits uint) (uint32, error) {
+	if err := s.check(bits); err != nil {
+		return 0, err
+	}
+	return s.r.Uint32(bits), nil
+}
+
+// Int32 reads up to 32 signed bits in big-endian order.
+func (s *SafeReader) Int32(bits uint) (int32, error) {
+	if err := s.check(bits); err != nil {
+		return 0, err
+	}
+	return s.r.Int32(bits), nil
+}
+
+// Uint64 reads up to 64 unsigned bits in big-endian order.
+func (s *SafeReader) Uint64(bits uint) (uint64, error) {
+	if err := s.check(bits); err != nil {
+		return 0, err
+	}
+	return s.r.Uint64(bits), nil
+}
+
+// Int64 reads up to 64 signed bits in big-endian order.
+func (s *SafeReader) Int64(bits uint) (int64, error) {
+	if err := s.check(bits); err != nil {
+		return 0, err
+	}
+	return s.r.Int64(bits), nil
+}
+
+// Bytes returns a byte-array of input size.
+func (s *SafeReader) Bytes(size int) ([]byte, error) {
+	if err := s.check(uint(size) * 8); err != nil {
+		return nil, err
+	}
+	return s.r.Bytes(size), nil
+}
+
+// Skip skips n bits.
+func (s *SafeReader) Skip(bits uint) error {
+	if err := s.check(bits); err != nil {
+		return err
+	}
+	s.r.Skip(bits)
+	return nil
+}
+
+// SafeWriter wraps a writer and checks bounds on every call, returning
+// errors immediately instead of deferring them to Flush.
+// Once a put has failed, every following call fails with the same error.
+// Like SafeReader, it trades speed for explicit error handling.
+type SafeWriter struct {
+	w   *Writer
+	err error
+}
+
+// NewSafeWriter returns a new safe writer writing to <w>.
+func NewSafeWriter(w *Writer) SafeWriter {
+	return SafeWriter{w: w}
+}
+
+func (s *SafeWriter) check(bits uint) error {
+	if s.err == nil && !s.w.CanWrite(bits) {
+		s.err = ErrOverflow
+	}
+	return s.err
+}
+
+// PutBit writes one bit to output.
+func (s *SafeWriter) PutBit(val bool) error {
+	if err := s.check(1); err != nil {
+		return err
+	}
+	s.w.PutBit(val)
+	return nil
+}
+
+// PutByte writes one byte.
+func (s *SafeWriter) PutByte(val byte) error {
+	if err := s.check(8); err != nil {
+		return err
+	}
+	s.w.PutByte(val)
+	return nil
+}
+
+// PutUint32 writes up to 32 bits in big-endian order.
+func (s *SafeWriter) PutUint32(bits uint, val uint32) error {
+	if err := s.check(bits); err != nil {
+		return err
+	}
+	s.w.PutUint32(bits, val)
+	return nil
+}
+
+// PutInt32 writes up to 32 signed bits in big-endian order.
+func (s *SafeWriter) PutInt32(bits uint, val int32) error {
+	if err := s.check(bits); err != nil {
+		return err
+	}
+	s.w.PutInt32(bits, val)
+	return nil
+}
+
+// PutUint64 writes up to 64 bits in big-endian order.
+func (s *SafeWriter) PutUint64(bits uint, val uint64) error {
+	if err := s.check(bits); err != nil {
+		return err
+	}
+	s.w.PutUint64(bits, val)
+	return nil
+}
+
+// PutInt64 writes up to 64 signed bits in big-endian order.
+func (s *SafeWriter) PutInt64(bits uint, val int64) error {
+	if err := s.check(bits); err != nil {
+		return err
+	}
+	s.w.PutInt64(bits, val)
+	return nil
+}
+
+// Flush flushes the underlying writer.
+func (s *SafeWriter) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
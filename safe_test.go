@@ -0,0 +1,68 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestSafeReader(t *testing.T) {
+	buf := []byte{0xC1, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF, 0x80}
+	r := NewReader(buf)
+	s := NewSafeReader(&r)
+	bit, err := s.Bit()
+	expect(t, true, bit)
+	expect(t, nil, err)
+	i32, err := s.Int32(7)
+	expect(t, int32(-63), i32)
+	expect(t, nil, err)
+	b, err := s.Byte()
+	expect(t, uint8(0x23), b)
+	expect(t, nil, err)
+	u32, err := s.Uint32(8)
+	expect(t, uint32(0x45), u32)
+	expect(t, nil, err)
+	expect(t, nil, s.Skip(8))
+	i64, err := s.Int64(33)
+	expect(t, int64(0x89ABCDEF<<1|1)-1<<33, i64)
+	expect(t, nil, err)
+	u64, err := s.Uint64(8)
+	expect(t, uint64(0), u64)
+	expect(t, ErrOverflow, err)
+	expect(t, uint(65), r.At())
+	p, err := s.Bytes(2)
+	expect(t, []byte(nil), p)
+	expect(t, ErrOverflow, err)
+	expect(t, ErrOverflow, s.Skip(9))
+	expect(t, nil, s.Skip(7))
+	_, err = s.Bit()
+	expect(t, ErrOverflow, err)
+	expect(t, nil, r.Error())
+}
+
+func TestSafeWriter(t *testing.T) {
+	buf := make([]byte, 8)
+	w := NewWriter(buf)
+	s := NewSafeWriter(&w)
+	expect(t, nil, s.PutBit(true))
+	expect(t, nil, s.PutInt32(7, -63))
+	expect(t, nil, s.PutByte(0x23))
+	expect(t, nil, s.PutUint32(8, 0x45))
+	expect(t, nil, s.PutInt64(33, -1))
+	expect(t, ErrOverflow, s.PutUint64(8, 0))
+	expect(t, 57, w.Index())
+	expect(t, ErrOverflow, s.PutBit(false))
+	expect(t, ErrOverflow, s.PutUint32(1, 0))
+	expect(t, ErrOverflow, s.Flush())
+	w.PutUint32(7, 0x7F)
+	expect(t, nil, w.Flush())
+	compare(t, buf, []byte{0xC1, 0x23, 0x45, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	w = NewWriter(buf)
+	s = NewSafeWriter(&w)
+	expect(t, nil, s.PutUint64(64, 0))
+	expect(t, nil, s.Flush())
+	expect(t, false, w.CanWrite(1))
+	expect(t, true, w.CanWrite(0))
+}
@@ -0,0 +1,69 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math"
+)
+
+// Saturating reads read fields of up to 64 bits and clamp them into a
+// narrower type. They also return whether the value had to be clamped,
+// which usually denotes a malformed input. The reader always advances by
+// the full field width.
+
+func (r *Reader) uintSat(bits uint, max uint64) (uint64, bool) {
+	v := r.Uint64(bits)
+	if v > max {
+		return max, true
+	}
+	return v, false
+}
+
+func (r *Reader) intSat(bits uint, min, max int64) (int64, bool) {
+	v := r.Int64(bits)
+	if v > max {
+		return max, true
+	}
+	if v < min {
+		return min, true
+	}
+	return v, false
+}
+
+// Uint8Sat reads up to 64 unsigned bits saturated to an uint8.
+func (r *Reader) Uint8Sat(bits uint) (uint8, bool) {
+	v, clamped := r.uintSat(bits, math.MaxUint8)
+	return uint8(v), clamped
+}
+
+// Uint16Sat reads up to 64 unsigned bits saturated to an uint16.
+func (r *Reader) Uint16Sat(bits uint) (uint16, bool) {
+	v, clamped := r.uintSat(bits, math.MaxUint16)
+	return uint16(v), clamped
+}
+
+// Uint32Sat reads up to 64 unsigned bits saturated to an uint32.
+func (r *Reader) Uint32Sat(bits uint) (uint32, bool) {
+	v, clamped := r.uintSat(bits, math.MaxUint32)
+	return uint32(v), clamped
+}
+
+// Int8Sat reads up to 64 signed bits saturated to an int8.
+func (r *Reader) Int8Sat(bits uint) (int8, bool) {
+	v, clamped := r.intSat(bits, math.MinInt8, math.MaxInt8)
+	return int8(v), clamped
+}
+
+// Int16Sat reads up to 64 signed bits saturated to an int16.
+func (r *Reader) Int16Sat(bits uint) (int16, bool) {
+	v, clamped := r.intSat(bits, math.MinInt16, math.MaxInt16)
+	return int16(v), clamped
+}
+
+// Int32Sat reads up to 64 signed bits saturated to an int32.
+func (r *Reader) Int32Sat(bits uint) (int32, bool) {
+	v, clamped := r.intSat(bits, math.MinInt32, math.MaxInt32)
+	return int32(v), clamped
+}
@@ -0,0 +1,53 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestSaturatingReads(t *testing.T) {
+	buf := []byte{0x0F, 0xF1, 0x00, 0x7F, 0xFF, 0x80, 0x00, 0x00, 0x01}
+	r := NewReader(buf)
+	u8, clamped := r.Uint8Sat(12)
+	expect(t, uint8(0xFF), u8)
+	expect(t, false, clamped)
+	u8, clamped = r.Uint8Sat(12)
+	expect(t, uint8(0xFF), u8)
+	expect(t, true, clamped)
+	r.Reset()
+	u16, clamped := r.Uint16Sat(24)
+	expect(t, uint16(0xFFFF), u16)
+	expect(t, true, clamped)
+	expect(t, uint(24), r.At())
+	r.Reset()
+	u32, clamped := r.Uint32Sat(32)
+	expect(t, uint32(0x0FF1007F), u32)
+	expect(t, false, clamped)
+	u32, clamped = r.Uint32Sat(40)
+	expect(t, uint32(0xFFFFFFFF), u32)
+	expect(t, true, clamped)
+	r.Reset()
+	i8, clamped := r.Int8Sat(4)
+	expect(t, int8(0), i8)
+	expect(t, false, clamped)
+	i8, clamped = r.Int8Sat(16)
+	expect(t, int8(-128), i8)
+	expect(t, true, clamped)
+	i16, clamped := r.Int16Sat(24)
+	expect(t, int16(32767), i16)
+	expect(t, true, clamped)
+	r.Reset()
+	r.Skip(40)
+	i32, clamped := r.Int32Sat(32)
+	expect(t, int32(-0x7FFFFFFF), i32)
+	expect(t, false, clamped)
+	r.Reset()
+	r.Skip(40)
+	i16, clamped = r.Int16Sat(32)
+	expect(t, int16(-32768), i16)
+	expect(t, true, clamped)
+	expect(t, nil, r.Error())
+}
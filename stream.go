@@ -0,0 +1,164 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+)
+
+const defaultStreamCache = 4096
+
+// StreamReader reads bits from an io.Reader, refilling a cache of input
+// bytes whenever a read runs past it.
+// Like Reader, its methods don't return errors: the first one is sticky &
+// reported by Error. Reads past the end of the input return zeros & flag
+// ErrOverflow while errors from the input or its context are reported as
+// is, after which reads return zeros too.
+type StreamReader struct {
+	ctx   context.Context
+	src   io.Reader
+	cache []byte
+	n     uint // cached bytes
+	idx   uint // bit position within the cache
+	read  uint // bits consumed before the cache
+	done  bool // no more refills
+	err   error
+}
+
+// NewStreamReader returns a new reader reading from <src> through a cache
+// of 4096 bytes.
+func NewStreamReader(src io.Reader) *StreamReader {
+	return newStreamReader(context.Background(), src, defaultStreamCache)
+}
+
+// NewStreamReaderSize returns a new reader reading from <src> through a
+// cache of <cacheBytes> bytes, at least 8 to hold any 64-bit window.
+// Each refill reads at most the cache size from <src>: larger caches need
+// fewer calls to <src>, which suits high-throughput inputs, while smaller
+// ones return sooner on slow inputs like network streams, at the cost of
+// more calls.
+func NewStreamReaderSize(src io.Reader, cacheBytes int) *StreamReader {
+	return newStreamReader(context.Background(), src, cacheBytes)
+}
+
+// NewStreamReaderContext returns a new reader reading from <src> until
+// <ctx> is done. Refills then stop & Error reports the context error, which
+// tells cancelled parsers apart from truncated inputs.
+// Reads already cached are still served.
+func NewStreamReaderContext(ctx context.Context, src io.Reader) *StreamReader {
+	return newStreamReader(ctx, src, defaultStreamCache)
+}
+
+func newStreamReader(ctx context.Context, src io.Reader, cacheBytes int) *StreamReader {
+	return &StreamReader{
+		ctx:   ctx,
+		src:   src,
+		cache: make([]byte, max(cacheBytes, 8)),
+	}
+}
+
+func (s *StreamReader) fail(err error) {
+	s.done = true
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// refill moves unread bytes to the front of the cache and reads from the
+// input until <bits> are cached, the cache is full or the input is done.
+func (s *StreamReader) refill(bits uint) {
+	skip := min(s.idx>>3, s.n)
+	s.n = uint(copy(s.cache, s.cache[skip:s.n]))
+	s.idx -= skip << 3
+	s.read += skip << 3
+	for s.n<<3 < s.idx+bits && !s.done {
+		if err := s.ctx.Err(); err != nil {
+			s.fail(err)
+			break
+		}
+		n, err := s.src.Read(s.cache[s.n:])
+		s.n += uint(n)
+		if err == io.EOF {
+			s.done = true
+		} else if err != nil {
+			s.fail(err)
+		}
+	}
+	// reads past the input see zeros
+	clear(s.cache[s.n:])
+}
+
+// Uint32 reads up to 32 unsigned bits in big-endian order.
+func (s *StreamReader) Uint32(bits uint) uint32 {
+	if s.idx+bits > s.n<<3 {
+		if !s.done {
+			s.refill(bits)
+		}
+		if s.idx+bits > s.n<<3 && s.err == nil {
+			s.err = ErrOverflow
+		}
+	}
+	var val uint64
+	if skip := s.idx >> 3; skip+8 <= uint(len(s.cache)) {
+		val = binary.BigEndian.Uint64(s.cache[skip:])
+	} else if skip < s.n {
+		var tail [8]byte
+		copy(tail[:], s.cache[skip:s.n])
+		val = binary.BigEndian.Uint64(tail[:])
+	}
+	shift := s.idx & 7
+	s.idx += bits
+	return uint32(val << shift >> (64 - bits))
+}
+
+// Bit reads the next bit as a boolean.
+func (s *StreamReader) Bit() bool {
+	return s.Uint32(1) != 0
+}
+
+// Int32 reads up to 32 signed bits in big-endian order.
+func (s *StreamReader) Int32(bits uint) int32 {
+	return int32(s.Uint32(bits)<<(32-bits)) >> (32 - bits)
+}
+
+// Uint64 reads up to 64 unsigned bits in big-endian order.
+func (s *StreamReader) Uint64(bits uint) uint64 {
+	if bits <= 32 {
+		return uint64(s.Uint32(bits))
+	}
+	hi := uint64(s.Uint32(bits - 32))
+	return hi<<32 | uint64(s.Uint32(32))
+}
+
+// Int64 reads up to 64 signed bits in big-endian order.
+func (s *StreamReader) Int64(bits uint) int64 {
+	return int64(s.Uint64(bits)<<(64-bits)) >> (64 - bits)
+}
+
+// Skip skips <bits> bits.
+func (s *StreamReader) Skip(bits uint) {
+	for ; bits > 32; bits -= 32 {
+		s.Uint32(32)
+	}
+	s.Uint32(bits)
+}
+
+// At returns the number of bits read so far.
+func (s *StreamReader) At() uint {
+	return s.read + s.idx
+}
+
+// Error returns whether the reader encountered an error.
+func (s *StreamReader) Error() error {
+	return s.err
+}
+
+// Check returns whether the reader encountered an error.
+// It is an alias of Error.
+func (s *StreamReader) Check() error {
+	return s.err
+}
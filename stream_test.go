@@ -0,0 +1,108 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+func testStreamReader(t *testing.T, src []byte, s *StreamReader) {
+	r := NewReader(src)
+	for r.At() < uint(len(src)*8)+96 {
+		bits := uint(rand.Intn(65))
+		switch rand.Intn(4) {
+		case 0:
+			expect(t, r.Uint32(bits/2), s.Uint32(bits/2))
+		case 1:
+			expect(t, r.Int32(bits/2), s.Int32(bits/2))
+		case 2:
+			expect(t, r.Uint64(bits), s.Uint64(bits))
+		case 3:
+			expect(t, r.Int64(bits), s.Int64(bits))
+		}
+		expect(t, r.At(), s.At())
+	}
+	s.Skip(1000)
+	expect(t, r.At()+1000, s.At())
+	expect(t, uint32(0), s.Uint32(32))
+	expect(t, ErrOverflow, s.Check())
+}
+
+func TestStreamReader(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 8, 9, 1021, defaultStreamCache*3 + 5} {
+		src := makeSource(size)
+		testStreamReader(t, src, NewStreamReader(bytes.NewReader(src)))
+		testStreamReader(t, src, NewStreamReader(iotest.OneByteReader(bytes.NewReader(src))))
+		testStreamReader(t, src, NewStreamReader(iotest.DataErrReader(bytes.NewReader(src))))
+	}
+	s := NewStreamReader(bytes.NewReader([]byte{0x80, 0x01}))
+	expect(t, true, s.Bit())
+	s.Skip(14)
+	expect(t, true, s.Bit())
+	expect(t, nil, s.Error())
+}
+
+// readSizes records the size of every read from r
+type readSizes struct {
+	r     io.Reader
+	sizes []int
+}
+
+func (rs *readSizes) Read(p []byte) (int, error) {
+	rs.sizes = append(rs.sizes, len(p))
+	return rs.r.Read(p)
+}
+
+func TestStreamReaderSize(t *testing.T) {
+	src := makeSource(100000)
+	for _, size := range []int{-1, 0, 7, 8, 9, 64, 1 << 16, 1 << 20} {
+		testStreamReader(t, src, NewStreamReaderSize(iotest.HalfReader(bytes.NewReader(src)), size))
+		rs := &readSizes{r: bytes.NewReader(src)}
+		testStreamReader(t, src, NewStreamReaderSize(rs, size))
+		cache := max(size, 8)
+		for _, n := range rs.sizes {
+			if n > cache {
+				t.Fatalf("cache %v: read %v bytes", size, n)
+			}
+		}
+		// refills read as much as the cache holds
+		if calls := len(rs.sizes); calls > len(src)/(cache-4)+2 {
+			t.Fatalf("cache %v: %v reads", size, calls)
+		}
+	}
+}
+
+func TestStreamReaderErrors(t *testing.T) {
+	errSource := errors.New("source error")
+	src := makeSource(16)
+	s := NewStreamReader(io.MultiReader(bytes.NewReader(src), iotest.ErrReader(errSource)))
+	s.Skip(16 * 8)
+	expect(t, nil, s.Error())
+	expect(t, uint32(0), s.Uint32(8))
+	expect(t, errSource, s.Error())
+	// cancelled contexts stop refills but cached bits are still served
+	src = makeSource(defaultStreamCache * 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	s = NewStreamReaderContext(ctx, bytes.NewReader(src))
+	r := NewReader(src)
+	expect(t, r.Uint32(32), s.Uint32(32))
+	cancel()
+	s.Skip(defaultStreamCache*8 - 32)
+	expect(t, nil, s.Check())
+	expect(t, uint32(0), s.Uint32(8))
+	expect(t, context.Canceled, s.Check())
+	expect(t, uint(defaultStreamCache*8+8), s.At())
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	s = NewStreamReaderContext(ctx, bytes.NewReader(src))
+	expect(t, uint32(0), s.Uint32(1))
+	expect(t, context.Canceled, s.Check())
+}
@@ -0,0 +1,96 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"bytes"
+	"strings"
+)
+
+// CString reads a null-terminated string and returns it without its
+// terminator. The reader is expected to be byte-aligned.
+// A string missing its terminator is read up to the end, which flags an
+// overflow.
+func (r *Reader) CString() string {
+	d := r.LeftBytes()
+	n := bytes.IndexByte(d, 0)
+	if n < 0 {
+		r.Skip(uint(len(d)) * 8)
+		r.Skip(8)
+		return string(d)
+	}
+	r.Skip(uint(n+1) * 8)
+	return string(d[:n])
+}
+
+// str reads <n> bytes as a string, flagging an overflow if fewer are left.
+func (r *Reader) str(n int) string {
+	d := r.LeftBytes()
+	s := string(d[:min(n, len(d))])
+	r.Skip(uint(n) * 8)
+	return s
+}
+
+// FixedString reads a string stored in <n> bytes and trims its trailing
+// null bytes. The reader is expected to be byte-aligned.
+func (r *Reader) FixedString(n uint) string {
+	return strings.TrimRight(r.str(int(n)), "\x00")
+}
+
+// PString8 reads a string prefixed by its 8-bit length, as Pascal strings
+// found in QuickTime atoms. The reader is expected to be byte-aligned.
+func (r *Reader) PString8() string {
+	return r.str(int(r.Byte()))
+}
+
+// PString16 reads a string prefixed by its 16-bit big-endian length.
+// The reader is expected to be byte-aligned.
+func (r *Reader) PString16() string {
+	return r.str(int(r.Be16()))
+}
+
+// PutCString writes <s> followed by a null terminator.
+// <s> must not contain any null byte.
+func (w *Writer) PutCString(s string) {
+	w.putString(s)
+	w.PutByte(0)
+}
+
+// putString writes every byte of <s>.
+func (w *Writer) putString(s string) {
+	for i := 0; i < len(s); i++ {
+		w.PutByte(s[i])
+	}
+}
+
+// PutFixedString writes <s> in exactly <n> bytes, truncating it or padding
+// it with null bytes.
+func (w *Writer) PutFixedString(n uint, s string) {
+	if uint(len(s)) > n {
+		s = s[:n]
+	}
+	w.putString(s)
+	w.Fill(0, int(n)-len(s))
+}
+
+// PutPString8 writes <s> prefixed by its 8-bit length.
+// Strings longer than 255 bytes are truncated.
+func (w *Writer) PutPString8(s string) {
+	if len(s) > 0xFF {
+		s = s[:0xFF]
+	}
+	w.PutByte(byte(len(s)))
+	w.putString(s)
+}
+
+// PutPString16 writes <s> prefixed by its 16-bit big-endian length.
+// Strings longer than 65535 bytes are truncated.
+func (w *Writer) PutPString16(s string) {
+	if len(s) > 0xFFFF {
+		s = s[:0xFFFF]
+	}
+	w.PutBe16(uint16(len(s)))
+	w.putString(s)
+}
@@ -0,0 +1,61 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestCString(t *testing.T) {
+	buf := make([]byte, 16)
+	w := NewWriter(buf)
+	w.PutCString("hello")
+	w.PutCString("")
+	w.PutCString("iobit")
+	w.PutByte(0xFF)
+	expect(t, 112, w.Index())
+	expect(t, nil, w.Flush())
+	compare(t, []byte("hello\x00\x00iobit\x00\xFF\x00\x00"), buf)
+	r := NewReader(buf[:14])
+	expect(t, "hello", r.CString())
+	expect(t, uint(48), r.At())
+	expect(t, "", r.CString())
+	expect(t, "iobit", r.CString())
+	expect(t, nil, r.Error())
+	expect(t, "\xFF", r.CString())
+	expect(t, ErrOverflow, r.Error())
+	r = NewReaderPolicy([]byte("abc"), OverflowSaturate)
+	expect(t, "abc", r.CString())
+	expect(t, uint(24), r.At())
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestStrings(t *testing.T) {
+	buf := make([]byte, 32)
+	w := NewWriter(buf)
+	w.PutFixedString(6, "abc")
+	w.PutFixedString(2, "xyz")
+	w.PutPString8("moov")
+	w.PutPString16("")
+	w.PutPString16("trak")
+	expect(t, nil, w.Flush())
+	compare(t, []byte("abc\x00\x00\x00xy\x04moov\x00\x00\x00\x04trak"), buf[:w.Index()/8])
+	r := NewReader(buf[:w.Index()/8])
+	expect(t, "abc", r.FixedString(6))
+	expect(t, "xy", r.FixedString(2))
+	expect(t, "moov", r.PString8())
+	expect(t, "", r.PString16())
+	expect(t, "trak", r.PString16())
+	expect(t, nil, r.Error())
+	r = NewReader([]byte("\x05abc"))
+	expect(t, "abc", r.PString8())
+	expect(t, ErrOverflow, r.Error())
+	r = NewReader([]byte("a\x00b\x00"))
+	expect(t, "a\x00b", r.FixedString(4))
+	long := string(make([]byte, 300))
+	w = NewCountingWriter()
+	w.PutPString8(long)
+	expect(t, 256*8, w.Index())
+}
@@ -0,0 +1,66 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TLVNode is one tag-length-value entry parsed by ParseTLV.
+// Containers have their entries in Children while other nodes keep their
+// raw payload in Value.
+type TLVNode struct {
+	Tag      uint32
+	Value    []byte
+	Children []TLVNode
+}
+
+// ParseTLV reads tag-length-value entries until the end of <r>, with tags
+// of <tagBits> bits & byte lengths of <lengthBits> bits, up to 32 each.
+// Entries whose tag is a <container> are parsed recursively.
+// Truncated entries are flagged on <r>, which must be checked once parsed.
+// Panics with ErrWidth if entries have neither tag nor length bits, as
+// they would never consume <r>.
+func ParseTLV(r *Reader, tagBits, lengthBits uint, container func(tag uint32) bool) []TLVNode {
+	if tagBits+lengthBits == 0 {
+		panic(ErrWidth)
+	}
+	var nodes []TLVNode
+	for r.More() {
+		n := TLVNode{Tag: r.Uint32(tagBits)}
+		sub := r.Slice(int(r.Uint32(lengthBits)))
+		if container(n.Tag) {
+			n.Children = ParseTLV(&sub, tagBits, lengthBits, container)
+			if err := sub.Error(); err != nil && r.err == nil {
+				r.err = err
+			}
+		} else {
+			n.Value = sub.LeftBytes()
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// String pretty-prints the node & its children, one per line.
+func (n TLVNode) String() string {
+	var b strings.Builder
+	n.format(&b, 0)
+	return b.String()
+}
+
+func (n TLVNode) format(b *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.Children == nil {
+		fmt.Fprintf(b, "%s0x%02X: % X\n", indent, n.Tag, n.Value)
+		return
+	}
+	fmt.Fprintf(b, "%s0x%02X {\n", indent, n.Tag)
+	for _, c := range n.Children {
+		c.format(b, depth+1)
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
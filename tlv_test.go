@@ -0,0 +1,58 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"testing"
+)
+
+func TestParseTLV(t *testing.T) {
+	src := []byte{
+		0x01, 0x02, 0xAA, 0xBB,
+		0x20, 0x09,
+		/**/ 0x02, 0x01, 0xCC,
+		/**/ 0x21, 0x04,
+		/**/ /**/ 0x03, 0x02, 0xDD, 0xEE,
+		0x04, 0x00,
+	}
+	container := func(tag uint32) bool { return tag&0x20 != 0 }
+	r := NewReader(src)
+	nodes := ParseTLV(&r, 8, 8, container)
+	expect(t, nil, r.Error())
+	expect(t, []TLVNode{
+		{Tag: 0x01, Value: []byte{0xAA, 0xBB}},
+		{Tag: 0x20, Children: []TLVNode{
+			{Tag: 0x02, Value: []byte{0xCC}},
+			{Tag: 0x21, Children: []TLVNode{
+				{Tag: 0x03, Value: []byte{0xDD, 0xEE}},
+			}},
+		}},
+		{Tag: 0x04, Value: []byte{}},
+	}, nodes)
+	expect(t, "0x20 {\n"+
+		"  0x02: CC\n"+
+		"  0x21 {\n"+
+		"    0x03: DD EE\n"+
+		"  }\n"+
+		"}\n", nodes[1].String())
+	// truncated nested entry
+	r = NewReader([]byte{0x20, 0x03, 0x01, 0x05, 0xAA})
+	nodes = ParseTLV(&r, 8, 8, container)
+	expect(t, ErrOverflow, r.Error())
+	expect(t, 1, len(nodes))
+	// 4-bit tags & 12-bit lengths
+	r = NewReader([]byte{0x50, 0x02, 0x12, 0x34})
+	nodes = ParseTLV(&r, 4, 12, container)
+	expect(t, nil, r.Error())
+	expect(t, []TLVNode{{Tag: 5, Value: []byte{0x12, 0x34}}}, nodes)
+	// entries without bits never progress
+	r = NewReader([]byte{0x00})
+	expectPanic(t, ErrWidth, func() { ParseTLV(&r, 0, 0, container) })
+	// untagged entries
+	r = NewReader([]byte{0x01, 0xAA, 0x00})
+	nodes = ParseTLV(&r, 0, 8, container)
+	expect(t, nil, r.Error())
+	expect(t, []TLVNode{{Value: []byte{0xAA}}, {Value: []byte{}}}, nodes)
+}
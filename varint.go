@@ -0,0 +1,148 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"math/bits"
+)
+
+// Varints are made of 8-bit groups holding 7 bits of value each, the high
+// bit being set on every group but the last one.
+// They are read & written from the current position, which is byte
+// aligned in every format using them.
+
+const maxVarintGroups = 10
+
+// VarintBE reads a big-endian varint, whose first group holds the most
+// significant bits.
+// Unaligned readers skip to the next byte boundary first.
+// Flags ErrTooLong past 10 groups, 64 bits of value or the maximum code
+// length, & ErrTruncated past the reader end.
+func (r *Reader) VarintBE() uint64 {
+	var val uint64
+	r.Align()
+	start := r.idx
+	for i := 0; i < maxVarintGroups; i++ {
+		b := r.Byte()
+		if val>>57 != 0 {
+			break
+		}
+		val = val<<7 | uint64(b&0x7F)
+		if r.codeTooLong(start) || r.codeTruncated() || b&0x80 == 0 {
+			return val
+		}
+	}
+	r.err = ErrTooLong
+	return val
+}
+
+// PutVarintBE writes a big-endian varint, whose first group holds the most
+// significant bits.
+func (w *Writer) PutVarintBE(val uint64) {
+	n := max(1, (bits.Len64(val)+6)/7)
+	for i := n - 1; i > 0; i-- {
+		w.PutByte(byte(val>>(7*i)) | 0x80)
+	}
+	w.PutByte(byte(val) & 0x7F)
+}
+
+// MidiVLQ reads a MIDI variable-length quantity, which is a big-endian
+// varint of at most 4 groups.
+// Flags ErrTooLong if the fourth group is not the last one or past the
+// maximum code length, & ErrTruncated past the reader end.
+func (r *Reader) MidiVLQ() uint32 {
+	var val uint32
+	start := r.idx
+	for i := 0; i < 4; i++ {
+		b := r.Byte()
+		val = val<<7 | uint32(b&0x7F)
+		if r.codeTooLong(start) || r.codeTruncated() {
+			return val
+		}
+		if b&0x80 == 0 {
+			return val
+		}
+	}
+	r.err = ErrTooLong
+	return val
+}
+
+// PutMidiVLQ writes a MIDI variable-length quantity.
+// Only the low 28 bits of <val> are written.
+func (w *Writer) PutMidiVLQ(val uint32) {
+	w.PutVarintBE(uint64(val & 0x0FFFFFFF))
+}
+
+// Uleb128 reads an unsigned LEB128 varint, as used by DWARF & WebAssembly,
+// whose first group holds the least significant bits.
+// Flags ErrTooLong past 10 groups, 64 bits of value or the maximum code
+// length, & ErrTruncated past the reader end.
+func (r *Reader) Uleb128() uint64 {
+	var val uint64
+	start := r.idx
+	for i := 0; i < maxVarintGroups; i++ {
+		b := r.Byte()
+		if i == maxVarintGroups-1 && b&0x7E != 0 {
+			// the last group only holds bit 63
+			break
+		}
+		val |= uint64(b&0x7F) << (7 * i)
+		if r.codeTooLong(start) || r.codeTruncated() || b&0x80 == 0 {
+			return val
+		}
+	}
+	r.err = ErrTooLong
+	return val
+}
+
+// Sleb128 reads a signed LEB128 varint, sign-extended from bit 6 of its
+// last group.
+// Errors are flagged like in Uleb128.
+func (r *Reader) Sleb128() int64 {
+	var val uint64
+	start := r.idx
+	for i := 0; i < maxVarintGroups; i++ {
+		b := r.Byte()
+		if i == maxVarintGroups-1 && b&0x7F != 0 && b&0x7F != 0x7F {
+			// the last group only holds bit 63 & its sign extension
+			break
+		}
+		val |= uint64(b&0x7F) << (7 * i)
+		if r.codeTooLong(start) || r.codeTruncated() {
+			return int64(val)
+		}
+		if b&0x80 == 0 {
+			if shift := 7 * (i + 1); shift < 64 && b&0x40 != 0 {
+				val |= ^uint64(0) << shift
+			}
+			return int64(val)
+		}
+	}
+	r.err = ErrTooLong
+	return int64(val)
+}
+
+// PutUleb128 writes an unsigned LEB128 varint.
+func (w *Writer) PutUleb128(val uint64) {
+	for val >= 0x80 {
+		w.PutByte(byte(val) | 0x80)
+		val >>= 7
+	}
+	w.PutByte(byte(val))
+}
+
+// PutSleb128 writes a signed LEB128 varint, whose last group holds the
+// sign in bit 6.
+func (w *Writer) PutSleb128(val int64) {
+	for {
+		b := byte(val) & 0x7F
+		val >>= 7
+		if val == 0 && b&0x40 == 0 || val == -1 && b&0x40 != 0 {
+			w.PutByte(b)
+			return
+		}
+		w.PutByte(b | 0x80)
+	}
+}
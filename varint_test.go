@@ -0,0 +1,196 @@
+// Copyright 2013 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package iobit
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// variable-length quantities from the standard MIDI file specification
+var midiVLQs = []struct {
+	val uint32
+	enc []byte
+}{
+	{0x00000000, []byte{0x00}},
+	{0x00000040, []byte{0x40}},
+	{0x0000007F, []byte{0x7F}},
+	{0x00000080, []byte{0x81, 0x00}},
+	{0x00002000, []byte{0xC0, 0x00}},
+	{0x00003FFF, []byte{0xFF, 0x7F}},
+	{0x00004000, []byte{0x81, 0x80, 0x00}},
+	{0x00100000, []byte{0xC0, 0x80, 0x00}},
+	{0x001FFFFF, []byte{0xFF, 0xFF, 0x7F}},
+	{0x00200000, []byte{0x81, 0x80, 0x80, 0x00}},
+	{0x08000000, []byte{0xC0, 0x80, 0x80, 0x00}},
+	{0x0FFFFFFF, []byte{0xFF, 0xFF, 0xFF, 0x7F}},
+}
+
+func TestVarintBE(t *testing.T) {
+	for _, v := range midiVLQs {
+		buf := make([]byte, len(v.enc))
+		w := NewWriter(buf)
+		w.PutVarintBE(uint64(v.val))
+		expect(t, nil, w.Flush())
+		compare(t, v.enc, buf)
+		r := NewReader(buf)
+		expect(t, uint64(v.val), r.VarintBE())
+		expect(t, uint(0), r.LeftBits())
+	}
+	buf := make([]byte, 10)
+	for _, v := range []uint64{math.MaxUint64, 1 << 63, 1<<56 - 1, 1 << 56} {
+		w := NewWriter(buf)
+		w.PutVarintBE(v)
+		expect(t, nil, w.Flush())
+		r := NewReader(buf)
+		expect(t, v, r.VarintBE())
+		expect(t, uint(w.Index()), r.At())
+	}
+	for i := range buf {
+		buf[i] = 0xFF
+	}
+	r := NewReader(append(buf, 0xFF, 0xFF))
+	r.VarintBE()
+	expect(t, ErrTooLong, r.Error())
+	expect(t, uint(80), r.At())
+	// 65 bits of value
+	r = NewReader([]byte{0x83, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F})
+	r.VarintBE()
+	expect(t, ErrTooLong, r.Error())
+	// unaligned readers skip to the next byte
+	r = NewReader([]byte{0xFF, 0x82, 0x2C})
+	r.Skip(3)
+	expect(t, uint64(300), r.VarintBE())
+	expect(t, uint(24), r.At())
+	expect(t, nil, r.Error())
+}
+
+func TestMidiVLQ(t *testing.T) {
+	for _, v := range midiVLQs {
+		buf := make([]byte, len(v.enc))
+		w := NewWriter(buf)
+		w.PutMidiVLQ(v.val)
+		expect(t, nil, w.Flush())
+		compare(t, v.enc, buf)
+		r := NewReader(buf)
+		expect(t, v.val, r.MidiVLQ())
+		expect(t, nil, r.Error())
+		expect(t, uint(0), r.LeftBits())
+	}
+	buf := make([]byte, 4)
+	w := NewWriter(buf)
+	w.PutMidiVLQ(0xFFFFFFFF)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{0xFF, 0xFF, 0xFF, 0x7F}, buf)
+	r := NewReader([]byte{0x81, 0x80, 0x80, 0x80, 0x00})
+	expect(t, uint32(0x00200000), r.MidiVLQ())
+	expect(t, ErrTooLong, r.Error())
+	expect(t, uint(32), r.At())
+	r.Reset()
+	expect(t, nil, r.Error())
+}
+
+func TestUleb128(t *testing.T) {
+	// from the DWARF specification, section 7.6
+	for _, v := range []struct {
+		val uint64
+		enc []byte
+	}{
+		{2, []byte{0x02}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x80, 0x01}},
+		{129, []byte{0x81, 0x01}},
+		{130, []byte{0x82, 0x01}},
+		{12857, []byte{0xB9, 0x64}},
+		{624485, []byte{0xE5, 0x8E, 0x26}},
+		{math.MaxUint64, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}},
+	} {
+		r := NewReader(v.enc)
+		expect(t, v.val, r.Uleb128())
+		expect(t, uint(len(v.enc)*8), r.At())
+		expect(t, nil, r.Error())
+	}
+	long := make([]byte, 11)
+	for i := range long {
+		long[i] = 0x80
+	}
+	r := NewReader(long)
+	r.Uleb128()
+	expect(t, ErrTooLong, r.Error())
+	expect(t, uint(80), r.At())
+	r = NewReader([]byte{0x80, 0x80})
+	r.Uleb128()
+	expect(t, ErrTruncated, r.Error())
+	// the 10th group only holds bit 63
+	long[9] = 0x02
+	r = NewReader(long)
+	r.Uleb128()
+	expect(t, ErrTooLong, r.Error())
+	expect(t, uint(80), r.At())
+}
+
+func TestLeb128(t *testing.T) {
+	// from the DWARF specification, section 7.6
+	for _, v := range []struct {
+		val int64
+		enc []byte
+	}{
+		{2, []byte{0x02}},
+		{-2, []byte{0x7E}},
+		{127, []byte{0xFF, 0x00}},
+		{-127, []byte{0x81, 0x7F}},
+		{128, []byte{0x80, 0x01}},
+		{-128, []byte{0x80, 0x7F}},
+		{129, []byte{0x81, 0x01}},
+		{-129, []byte{0xFF, 0x7E}},
+	} {
+		r := NewReader(v.enc)
+		expect(t, v.val, r.Sleb128())
+		expect(t, uint(len(v.enc)*8), r.At())
+		buf := make([]byte, len(v.enc))
+		w := NewWriter(buf)
+		w.PutSleb128(v.val)
+		expect(t, nil, w.Flush())
+		compare(t, v.enc, buf)
+	}
+	signed := []int64{-1, 0, 63, 64, -64, -65, math.MinInt64, math.MaxInt64}
+	unsigned := []uint64{0, 127, 128, 624485, math.MaxUint64}
+	buf := make([]byte, (len(signed)+len(unsigned))*10)
+	w := NewWriter(buf)
+	for _, v := range signed {
+		w.PutSleb128(v)
+	}
+	for _, v := range unsigned {
+		w.PutUleb128(v)
+	}
+	expect(t, nil, w.Flush())
+	r := NewReader(buf)
+	for _, v := range signed {
+		expect(t, v, r.Sleb128())
+	}
+	for _, v := range unsigned {
+		expect(t, v, r.Uleb128())
+	}
+	expect(t, nil, r.Error())
+	expect(t, uint(len(buf)*8-w.Bits()), r.At())
+	long := bytes.Repeat([]byte{0x80}, 11)
+	r = NewReader(long)
+	r.Sleb128()
+	expect(t, ErrTooLong, r.Error())
+	expect(t, uint(80), r.At())
+	// the 10th group only holds bit 63 & its sign extension
+	for _, last := range []byte{0x01, 0x02, 0x3F, 0x40, 0x7E} {
+		long[9] = last
+		r = NewReader(long)
+		r.Sleb128()
+		expect(t, ErrTooLong, r.Error())
+		expect(t, uint(80), r.At())
+	}
+	long[9] = 0x7F
+	r = NewReader(long)
+	expect(t, int64(math.MinInt64), r.Sleb128())
+	expect(t, nil, r.Error())
+}
@@ -7,16 +7,28 @@ package iobit
 import (
 	"encoding/binary"
 	"errors"
+	"hash/crc32"
+	"io"
 )
 
 // Writer wraps a raw byte array and provides multiple methoods to write data bit-by-bit
 // Its methods don't return the usual error as it is too expensive.
 // Instead, write errors can be checked with the Flush() method.
 type Writer struct {
-	dst   []byte
-	cache uint64
-	fill  uint
-	idx   int
+	dst    []byte
+	cache  uint64
+	fill   uint
+	room   uint // writes filling the cache up to this size take the fast path
+	idx    int
+	end    int
+	count  bool
+	policy OverflowPolicy
+	out    io.Writer
+	sent   int
+	table  *crc32.Table
+	crc    uint32
+	crcIdx int
+	err    error
 }
 
 var (
@@ -25,17 +37,75 @@ var (
 
 	// ErrUnderflow happens when flushing unaligned writers
 	ErrUnderflow = errors.New("bit underflow")
+
+	// ErrReserved happens when reserved bits don't match their mandated value
+	ErrReserved = errors.New("invalid reserved bits")
+
+	// ErrTooLong happens when a variable-length code exceeds its maximum size
+	ErrTooLong = errors.New("variable-length code too long")
+
+	// ErrTruncated happens when a variable-length code runs past the input
+	ErrTruncated = errors.New("truncated variable-length code")
+
+	// ErrPadding happens when strict readers read past their input
+	ErrPadding = errors.New("read into padding bits")
 )
 
 // NewWriter returns a new writer writing to output byte array.
 func NewWriter(dst []byte) Writer {
-	return Writer{dst: dst}
+	return Writer{
+		dst:  dst,
+		room: 64,
+		end:  len(dst) << 3,
+	}
+}
+
+// NewWriterLimit returns a new writer writing at most <bits> bits to
+// <dst>, as if it ended there.
+// Bytes past the limit are never modified.
+func NewWriterLimit(dst []byte, bits int) Writer {
+	size := min(len(dst), (bits+7)>>3)
+	w := NewWriter(dst[:size])
+	w.end = min(w.end, bits)
+	return w
+}
+
+// NewWriterCRC returns a new writer writing to <dst> which computes a
+// running CRC-32 over its output with <table>, see CRC.
+func NewWriterCRC(dst []byte, table *crc32.Table) Writer {
+	w := NewWriter(dst)
+	w.table = table
+	return w
+}
+
+// NewCountingWriter returns a new writer without any storage.
+// It only counts written bits so that Index returns the exact size an
+// encoding would take with a regular writer.
+// As nothing is stored, Bits always returns 0 and Bytes an empty slice.
+func NewCountingWriter() Writer {
+	return Writer{room: 64, count: true}
 }
 
 // PutUint32 writes up to 32 bits in big-endian order.
 func (w *Writer) PutUint32(bits uint, val uint32) {
+	if w.fill+bits > w.room {
+		slowPut(w, bits, val, (*Writer).putSlow)
+		return
+	}
+	w.cache |= uint64(val) << (64 - bits) >> w.fill
+	w.fill += bits
+}
+
+// putSlow is PutUint32 for full caches & policies
+func (w *Writer) putSlow(bits uint, val uint32) {
+	if w.policy != OverflowIgnore && w.drop(bits) {
+		return
+	}
 	u := uint64(val) << (64 - bits)
 	if w.fill > 64-bits {
+		if w.idx+4 > len(w.dst) && w.out != nil {
+			w.drain()
+		}
 		if w.idx+4 <= len(w.dst) {
 			binary.BigEndian.PutUint32(w.dst[w.idx:], uint32(w.cache>>32))
 		}
@@ -48,8 +118,17 @@ func (w *Writer) PutUint32(bits uint, val uint32) {
 	w.cache |= u
 }
 
+// slowPut calls putSlow through a function parameter, which the inliner
+// charges little for, see slowRead.
+func slowPut(w *Writer, bits uint, val uint32, put func(*Writer, uint, uint32)) {
+	put(w, bits, val)
+}
+
 // PutUint64 writes up to 64 bits in big-endian order.
 func (w *Writer) PutUint64(bits uint, val uint64) {
+	if w.policy != OverflowIgnore && w.drop(bits) {
+		return
+	}
 	if bits > 32 {
 		bits -= 32
 		w.PutBe32(uint32(val >> bits))
@@ -57,6 +136,49 @@ func (w *Writer) PutUint64(bits uint, val uint64) {
 	w.PutUint32(bits, uint32(val))
 }
 
+// PutUint128Array writes up to 128 unsigned bits in big-endian order from
+// {high, low} 64-bit halves.
+func (w *Writer) PutUint128Array(bits uint, val [2]uint64) {
+	if bits > 64 {
+		w.PutUint64(bits-64, val[0])
+		bits = 64
+	}
+	w.PutUint64(bits, val[1])
+}
+
+// PutGroups writes the low <k> bits, up to 8, of every byte in <groups>.
+func (w *Writer) PutGroups(k uint, groups []byte) {
+	for _, v := range groups {
+		w.PutUint8(k, v)
+	}
+}
+
+// Fill writes <n> bytes set to <b>.
+// Byte-aligned writers fill their output directly, which is much faster
+// than writing bytes one by one.
+func (w *Writer) Fill(b byte, n int) {
+	if w.policy != OverflowIgnore && w.drop(uint(n)*8) {
+		return
+	}
+	if w.fill&7 != 0 || w.out != nil {
+		for ; n > 0; n-- {
+			w.PutUint32(8, uint32(b))
+		}
+		return
+	}
+	w.store()
+	if w.idx < len(w.dst) {
+		p := w.dst[w.idx:min(w.idx+n, len(w.dst))]
+		if len(p) > 0 {
+			p[0] = b
+		}
+		for i := 1; i < len(p); i *= 2 {
+			copy(p[i:], p[:i])
+		}
+	}
+	w.idx += n
+}
+
 // PutBit writes one bit to output.
 func (w *Writer) PutBit(val bool) {
 	v := uint32(0)
@@ -66,6 +188,19 @@ func (w *Writer) PutBit(val bool) {
 	w.PutUint32(1, v)
 }
 
+// PutReserved writes n reserved bits set to val.
+func (w *Writer) PutReserved(bits uint, val bool) {
+	v := uint32(0)
+	if val {
+		v = ^v
+	}
+	for bits > 32 {
+		w.PutUint32(32, v)
+		bits -= 32
+	}
+	w.PutUint32(bits, v)
+}
+
 // PutByte writes one byte.
 func (w *Writer) PutByte(val byte) {
 	w.PutUint32(8, uint32(val))
@@ -133,19 +268,99 @@ func (w *Writer) PutInt64(bits uint, val int64) {
 	w.PutUint64(bits, uint64(val))
 }
 
+// PutInt16Le writes up to 16 signed bits in little-endian order, like
+// Int16Le reads them: the first 8 bits are the low byte & the remaining
+// ones the high byte.
+func (w *Writer) PutInt16Le(bits uint, val int16) {
+	w.putIntLe(bits, int64(val))
+}
+
+// PutInt32Le writes up to 32 signed bits in little-endian order, from the
+// low byte up, the last group holding the remaining high bits.
+func (w *Writer) PutInt32Le(bits uint, val int32) {
+	w.putIntLe(bits, int64(val))
+}
+
+// PutInt64Le writes up to 64 signed bits in little-endian order, from the
+// low byte up, the last group holding the remaining high bits.
+func (w *Writer) PutInt64Le(bits uint, val int64) {
+	w.putIntLe(bits, val)
+}
+
+func (w *Writer) putIntLe(bits uint, val int64) {
+	for ; bits > 8; bits -= 8 {
+		w.PutByte(byte(val))
+		val >>= 8
+	}
+	w.PutUint32(bits, uint32(val))
+}
+
+// Optional writes a presence bit followed, if <present> is set, by up to
+// 32 bits of <val>.
+func (w *Writer) Optional(present bool, bits uint, val uint32) {
+	w.PutBit(present)
+	if present {
+		w.PutUint32(bits, val)
+	}
+}
+
+// PutXor writes up to 32 bits of <val> xored with the low bits of <key>.
+func (w *Writer) PutXor(bits uint, val uint32, key uint64) {
+	w.PutUint32(bits, val^uint32(key))
+}
+
+// PutRLE writes a run made of a <countBits> count followed by a
+// <valueBits> value, each up to 32 bits.
+func (w *Writer) PutRLE(countBits, valueBits uint, count, value uint32) {
+	w.PutUint32(countBits, count)
+	w.PutUint32(valueBits, value)
+}
+
+// PutFrame writes one frame of interleaved signed samples of up to 32 bits
+// each.
+func (w *Writer) PutFrame(bits uint, frame []int32) {
+	for _, v := range frame {
+		w.PutInt32(bits, v)
+	}
+}
+
 // Flush flushes the writer to its underlying buffer.
 // Returns ErrUnderflow if the output is not byte-aligned.
 // Returns ErrOverflow if the output array is too small.
 func (w *Writer) Flush() error {
+	if w.count {
+		return w.flushCount()
+	}
+	if w.out != nil {
+		return w.flushBridge()
+	}
+	w.store()
+	if w.err != nil {
+		return w.err
+	}
+	if w.Index() > w.end {
+		return ErrOverflow
+	}
+	if w.fill != 0 {
+		return ErrUnderflow
+	}
+	return nil
+}
+
+// store moves every complete cached byte to output.
+func (w *Writer) store() {
 	for w.fill >= 8 && w.idx < len(w.dst) {
 		w.dst[w.idx] = byte(w.cache >> 56)
 		w.idx++
 		w.cache <<= 8
 		w.fill -= 8
 	}
-	if w.idx+int(w.fill) > len(w.dst) {
-		return ErrOverflow
-	}
+}
+
+func (w *Writer) flushCount() error {
+	w.idx += int(w.fill >> 3)
+	w.cache <<= w.fill &^ 7
+	w.fill &= 7
 	if w.fill != 0 {
 		return ErrUnderflow
 	}
@@ -159,6 +374,18 @@ func (w *Writer) Write(p []byte) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	if w.count {
+		w.idx += len(p)
+		return len(p), nil
+	}
+	if w.out != nil {
+		n, err := w.out.Write(p)
+		w.sent += n
+		return n, err
+	}
+	if w.policy != OverflowIgnore && w.drop(uint(len(p)*8)) {
+		return 0, ErrOverflow
+	}
 	n := 0
 	if w.idx < len(w.dst) {
 		n = copy(w.dst[w.idx:], p)
@@ -170,22 +397,114 @@ func (w *Writer) Write(p []byte) (int, error) {
 	return n, nil
 }
 
-// Index returns the current writer position in bits.
-func (w *Writer) Index() int {
-	return w.idx<<3 + int(w.fill)
+// Align pads with bits set to <pad> up to the next byte boundary and
+// returns the number of pad bits.
+func (w *Writer) Align(pad bool) uint {
+	bits := uint(-w.Index() & 7)
+	v := uint32(0)
+	if pad {
+		v = 0xFF
+	}
+	w.PutUint32(bits, v)
+	return bits
 }
 
-func imin(a, b int) int {
-	if a > b {
-		return b
+// PutAlignedBytes pads with zeros up to the next byte boundary then
+// writes p at once.
+// Returns the number of pad bits and ErrOverflow if p doesn't fit.
+func (w *Writer) PutAlignedBytes(p []byte) (uint, error) {
+	pad := uint(-w.Index() & 7)
+	w.PutUint32(pad, 0)
+	_, err := w.Write(p)
+	return pad, err
+}
+
+// Patch overwrites <bits> bits, up to 32, at absolute position <bitPos>
+// with <val>, such as a length field written before its payload.
+// The region must lie within bytes already moved out of the cache, which
+// Patch first does for every complete byte.
+// Returns ErrOverflow if the region overlaps the last partial byte or is
+// out of the output, & never modifies anything then.
+func (w *Writer) Patch(bitPos int, bits uint, val uint32) error {
+	w.store()
+	top := min(w.idx, len(w.dst)) << 3
+	if w.count {
+		top = w.Index() &^ 7
+	}
+	pos := bitPos - w.sent<<3
+	if bitPos < 0 || pos < 0 || pos+int(bits) > top {
+		return ErrOverflow
+	}
+	if w.count {
+		return nil
 	}
-	return a
+	for i := uint(0); i < bits; i++ {
+		p := uint(pos) + i
+		shift := 7 - p&7
+		bit := byte(val>>(bits-1-i)) & 1
+		w.dst[p>>3] = w.dst[p>>3]&^(1<<shift) | bit<<shift
+	}
+	return nil
+}
+
+// Marker locates a field reserved by Writer.Reserve.
+type Marker struct {
+	pos  int
+	bits uint
+}
+
+// Reserve writes <bits> zero bits, up to 64, to be filled later with
+// FillMarker, such as a size only known once its body is written.
+func (w *Writer) Reserve(bits uint) Marker {
+	m := Marker{pos: w.Index(), bits: bits}
+	w.PutUint64(bits, 0)
+	return m
+}
+
+// FillMarker writes <val> into the field reserved as <m>.
+// Fails like Patch if the field is not entirely out of the cache yet.
+func (w *Writer) FillMarker(m Marker, val uint64) error {
+	if m.bits <= 32 {
+		return w.Patch(m.pos, m.bits, uint32(val))
+	}
+	hi := m.bits - 32
+	// check the field start so that nothing is modified on error
+	err := w.Patch(m.pos, 0, 0)
+	if err == nil {
+		err = w.Patch(m.pos+int(hi), 32, uint32(val))
+	}
+	if err == nil {
+		err = w.Patch(m.pos, hi, uint32(val>>32))
+	}
+	return err
+}
+
+// Measure runs <fn> on the writer and returns the number of bits it
+// wrote.
+func (w *Writer) Measure(fn func(*Writer)) int {
+	start := w.Index()
+	fn(w)
+	return w.Index() - start
+}
+
+// Index returns the current writer position in bits.
+func (w *Writer) Index() int {
+	return (w.sent+w.idx)<<3 + int(w.fill)
 }
 
 // Bits returns the number of bits available to write.
 func (w *Writer) Bits() int {
-	size := len(w.dst)
-	return size<<3 - imin(w.idx<<3+int(w.fill), size<<3)
+	return w.end - min(w.idx<<3+int(w.fill), w.end)
+}
+
+// CanWrite returns whether <bits> bits can be written without overflow.
+func (w *Writer) CanWrite(bits uint) bool {
+	return uint(w.Bits()) >= bits
+}
+
+// HasRoom returns whether at least one bit is available to write.
+func (w *Writer) HasRoom() bool {
+	return w.Bits() > 0
 }
 
 // Bytes returns a byte array of what's left to write.
@@ -198,8 +517,53 @@ func (w *Writer) Bytes() []byte {
 	return w.dst[skip:len(w.dst)]
 }
 
+// Remaining flushes the writer and returns the unwritten portion of its
+// output, starting with the partially written byte if any, & the number of
+// bits already written in that first byte, so that another encoder can
+// take over in the same buffer.
+// Writing into the returned slice bypasses the writer, which neither
+// advances nor accounts for those bits.
+func (w *Writer) Remaining() (dst []byte, bitOffset uint) {
+	w.Flush()
+	bitOffset = uint(w.Index() & 7)
+	if w.idx >= len(w.dst) {
+		return w.dst[:0], bitOffset
+	}
+	if w.fill != 0 {
+		w.dst[w.idx] = byte(w.cache >> 56)
+	}
+	return w.dst[w.idx:], bitOffset
+}
+
+// CRC returns the CRC-32 of every complete byte written so far by writers
+// created with NewWriterCRC, & zero otherwise.
+// Complete bytes are first moved out of the cache. Bytes modified by Patch
+// after being covered by a CRC call are not accounted again.
+func (w *Writer) CRC() uint32 {
+	if w.table == nil {
+		return 0
+	}
+	w.store()
+	w.updateCRC()
+	return w.crc
+}
+
+// updateCRC extends the running CRC over stored bytes.
+func (w *Writer) updateCRC() {
+	top := min(w.idx, len(w.dst))
+	if w.table != nil && top > w.crcIdx {
+		w.crc = crc32.Update(w.crc, w.table, w.dst[w.crcIdx:top])
+		w.crcIdx = top
+	}
+}
+
 // Reset resets the writer to its initial position.
 func (w *Writer) Reset() {
+	w.err = nil
+	w.cache = 0
 	w.fill = 0
 	w.idx = 0
+	w.sent = 0
+	w.crc = 0
+	w.crcIdx = 0
 }
@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"hash/crc32"
+	"math"
 	"math/rand"
 	"reflect"
 	"runtime"
@@ -152,6 +154,7 @@ func TestWriteHelpers(t *testing.T) {
 	w.PutUint32(1, 0)
 	expect(t, int(1), w.Index())
 	expect(t, int(7), w.Bits())
+	expect(t, true, w.HasRoom())
 	w.PutUint32(1, 1)
 	w.PutUint32(5, 0)
 	w.PutUint32(1, 1)
@@ -159,6 +162,7 @@ func TestWriteHelpers(t *testing.T) {
 	expect(t, buf, []byte{0x41})
 	expect(t, int(8), w.Index())
 	expect(t, int(0), w.Bits())
+	expect(t, false, w.HasRoom())
 	expect(t, 0, len(w.Bytes()))
 	expect(t, nil, err)
 	w.PutUint32(1, 0)
@@ -226,6 +230,497 @@ func TestBadSlices(t *testing.T) {
 	expect(t, 0, len(w.Bytes()))
 }
 
+func TestPutReserved(t *testing.T) {
+	buf := make([]byte, 7)
+	w := NewWriter(buf)
+	w.PutReserved(12, false)
+	w.PutReserved(35, true)
+	w.PutReserved(0, true)
+	w.PutReserved(1, false)
+	w.PutReserved(8, true)
+	expect(t, nil, w.Flush())
+	compare(t, buf, []byte{0x00, 0x0F, 0xFF, 0xFF, 0xFF, 0xFE, 0xFF})
+	r := NewReader(buf)
+	expect(t, nil, r.Reserved(12))
+	expect(t, nil, r.ReservedOnes(35))
+	expect(t, nil, r.Reserved(1))
+	expect(t, nil, r.ReservedOnes(8))
+}
+
+func TestEmptyWriter(t *testing.T) {
+	for _, dst := range [][]byte{nil, {}} {
+		w := NewWriter(dst)
+		expect(t, 0, w.Bits())
+		expect(t, false, w.HasRoom())
+		w.PutUint32(7, 0x7F)
+		w.PutUint64(64, 0)
+		w.PutBe32(0xFFFFFFFF)
+		w.PutByte(0xFF)
+		expect(t, 7+64+32+8, w.Index())
+		expect(t, 0, w.Bits())
+		expect(t, 0, len(w.Bytes()))
+		expect(t, ErrOverflow, w.Flush())
+		expect(t, 0, len(w.Bytes()))
+		n, err := w.Write([]byte{0x01})
+		expect(t, 0, n)
+		expect(t, ErrOverflow, err)
+		w.Reset()
+		expect(t, 0, w.Index())
+		expect(t, nil, w.Flush())
+	}
+	// unflushed bits must not leak after a reset
+	buf := make([]byte, 1)
+	w := NewWriter(buf)
+	w.PutUint32(7, 0x7F)
+	w.Reset()
+	w.PutByte(0x00)
+	expect(t, nil, w.Flush())
+	compare(t, buf, []byte{0x00})
+}
+
+func TestPutAlignedBytes(t *testing.T) {
+	buf := make([]byte, 6)
+	w := NewWriter(buf)
+	pad, err := w.PutAlignedBytes([]byte{0x01})
+	expect(t, uint(0), pad)
+	expect(t, nil, err)
+	w.PutUint32(3, 7)
+	pad, err = w.PutAlignedBytes([]byte{0x02, 0x03})
+	expect(t, uint(5), pad)
+	expect(t, nil, err)
+	w.PutBit(true)
+	pad, err = w.PutAlignedBytes([]byte{0x04, 0x05})
+	expect(t, uint(7), pad)
+	expect(t, ErrOverflow, err)
+	compare(t, buf, []byte{0x01, 0xE0, 0x02, 0x03, 0x80, 0x04})
+}
+
+func TestSignedLeWrites(t *testing.T) {
+	buf := make([]byte, 15)
+	w := NewWriter(buf)
+	w.PutInt16Le(16, -2)
+	w.PutInt32Le(32, -0x12345678)
+	w.PutInt64Le(64, math.MinInt64+1)
+	w.PutUint32(8, 0)
+	expect(t, nil, w.Flush())
+	compare(t, []byte{
+		0xFE, 0xFF,
+		0x88, 0xA9, 0xCB, 0xED,
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80,
+		0x00,
+	}, buf)
+	for _, skip := range []uint{0, 5} {
+		src := makeSource(64)
+		r := NewReader(src)
+		buf = make([]byte, len(src)+1)
+		w = NewWriter(buf)
+		w.PutUint32(skip, 0)
+		var i16 []int16
+		var i32 []int32
+		var i64 []int64
+		for r.LeftBits() >= 112 {
+			i16 = append(i16, int16(r.Be16()))
+			i32 = append(i32, int32(r.Be32()))
+			i64 = append(i64, int64(r.Be64()))
+			w.PutInt16Le(16, i16[len(i16)-1])
+			w.PutInt32Le(32, i32[len(i32)-1])
+			w.PutInt64Le(64, i64[len(i64)-1])
+		}
+		w.PutUint32(8-skip, 0)
+		expect(t, nil, w.Flush())
+		r = NewReader(buf)
+		r.Skip(skip)
+		for i := range i16 {
+			expect(t, i16[i], r.Int16Le(16))
+			expect(t, i32[i], int32(r.Le32()))
+			expect(t, i64[i], int64(r.Le64()))
+		}
+		expect(t, nil, r.Error())
+	}
+	// every width, the last group holding the remaining sign-extended bits
+	readLe := func(r *Reader, bits uint) int64 {
+		var val uint64
+		shift := uint(0)
+		for ; bits > 8; bits -= 8 {
+			val |= uint64(r.Uint8(8)) << shift
+			shift += 8
+		}
+		return int64(val) | r.Int64(bits)<<shift
+	}
+	for bits := uint(1); bits <= 64; bits++ {
+		for i := 0; i < 16; i++ {
+			v := int64(rand.Uint64()) >> (64 - bits)
+			buf = make([]byte, 24)
+			w = NewWriter(buf)
+			if bits <= 16 {
+				w.PutInt16Le(bits, int16(v))
+			}
+			if bits <= 32 {
+				w.PutInt32Le(bits, int32(v))
+			}
+			w.PutInt64Le(bits, v)
+			n := uint(w.Index())
+			w.PutUint32(uint(-w.Index()&7), 0)
+			expect(t, nil, w.Flush())
+			r := NewReader(buf)
+			if bits <= 16 {
+				expect(t, int16(v), r.Int16Le(bits))
+			}
+			if bits <= 32 {
+				expect(t, v, readLe(&r, bits))
+			}
+			expect(t, v, readLe(&r, bits))
+			expect(t, n, r.At())
+		}
+	}
+}
+
+func TestPatch(t *testing.T) {
+	buf := make([]byte, 6)
+	w := NewWriter(buf)
+	w.PutUint32(3, 0x7)
+	w.PutUint32(13, 0)
+	w.PutBe16(0xFFFF)
+	w.PutUint32(5, 0)
+	expect(t, nil, w.Patch(3, 12, 0xABC))
+	expect(t, nil, w.Patch(0, 1, 0))
+	expect(t, ErrOverflow, w.Patch(30, 4, 0))
+	expect(t, ErrOverflow, w.Patch(-1, 4, 0))
+	expect(t, nil, w.Patch(16, 16, 0x1234))
+	w.PutUint32(3, 0x7)
+	expect(t, nil, w.Flush())
+	expect(t, []byte{0x75, 0x78, 0x12, 0x34, 0x07, 0x00}, buf)
+	w = NewCountingWriter()
+	w.PutUint32(20, 0)
+	expect(t, nil, w.Patch(0, 16, 0x1234))
+	expect(t, ErrOverflow, w.Patch(8, 16, 0x1234))
+	// patching bridged bytes already sent fails
+	out := bytes.Buffer{}
+	w = NewBridgeWriter(&out, 8)
+	w.Fill(0, 12)
+	expect(t, nil, w.Flush())
+	expect(t, ErrOverflow, w.Patch(88, 8, 0xFF))
+}
+
+func TestReserve(t *testing.T) {
+	buf := make([]byte, 16)
+	w := NewWriter(buf)
+	w.PutUint32(4, 0xA)
+	size := w.Reserve(12)
+	crc := w.Reserve(40)
+	start := w.Index()
+	w.PutBe32(0x01020304)
+	w.PutUint32(8, 0)
+	expect(t, ErrOverflow, w.FillMarker(Marker{pos: 120, bits: 8}, 0))
+	expect(t, nil, w.FillMarker(size, uint64(w.Index()-start)))
+	expect(t, nil, w.FillMarker(crc, 0x123456789A))
+	expect(t, nil, w.Flush())
+	compare(t, []byte{
+		0xA0, 0x28, 0x12, 0x34, 0x56, 0x78, 0x9A,
+		0x01, 0x02, 0x03, 0x04, 0, 0, 0, 0, 0,
+	}, buf)
+	// fields overlapping the partial byte can't be filled
+	w = NewWriter(buf)
+	w.PutUint32(4, 0)
+	m := w.Reserve(8)
+	expect(t, ErrOverflow, w.FillMarker(m, 0))
+	m = w.Reserve(33)
+	expect(t, ErrOverflow, w.FillMarker(m, 0))
+}
+
+func TestWriterCRC(t *testing.T) {
+	buf := make([]byte, 64)
+	w := NewWriterCRC(buf, crc32.IEEETable)
+	w.Write([]byte("IEND"))
+	expect(t, uint32(0xAE426082), w.CRC())
+	w.PutBe32(w.CRC())
+	// CRC moves complete bytes out of the cache first
+	crc := w.CRC()
+	expect(t, crc32.ChecksumIEEE(buf[:8]), crc)
+	src := makeSource(48)
+	r := NewReader(src)
+	for _, bits := range []uint{3, 17, 32, 5, 7, 13, 1} {
+		for i := 0; i < 4; i++ {
+			w.PutUint32(bits, r.Uint32(bits))
+			crc = w.CRC()
+			expect(t, crc32.ChecksumIEEE(buf[:w.Index()/8]), crc)
+		}
+	}
+	w.Fill(0xAA, 3)
+	crc = w.CRC()
+	expect(t, crc32.ChecksumIEEE(buf[:w.Index()/8]), crc)
+	w.Reset()
+	expect(t, uint32(0), w.CRC())
+	w.PutByte(0x61)
+	crc = w.CRC()
+	expect(t, crc32.ChecksumIEEE([]byte("a")), crc)
+	w = NewWriter(buf)
+	w.PutByte(0x61)
+	expect(t, uint32(0), w.CRC())
+}
+
+func TestWriterAlign(t *testing.T) {
+	buf := make([]byte, 3)
+	w := NewWriter(buf)
+	expect(t, uint(0), w.Align(true))
+	w.PutUint32(3, 0x2)
+	expect(t, uint(5), w.Align(true))
+	expect(t, uint(0), w.Align(false))
+	w.PutBit(true)
+	expect(t, uint(7), w.Align(false))
+	w.PutUint32(4, 0)
+	expect(t, uint(4), w.Align(true))
+	expect(t, nil, w.Flush())
+	expect(t, []byte{0x5F, 0x80, 0x0F}, buf)
+}
+
+func TestPutOptional(t *testing.T) {
+	buf := make([]byte, 2)
+	w := NewWriter(buf)
+	w.Optional(true, 4, 0xA)
+	w.Optional(false, 4, 0xF)
+	w.Optional(true, 5, 0x16)
+	expect(t, 12, w.Index())
+	w.PutUint32(4, 0)
+	expect(t, nil, w.Flush())
+	compare(t, buf, []byte{0xD3, 0x60})
+	r := NewReader(buf)
+	for _, bits := range []uint{4, 4, 5} {
+		v, ok := r.Optional(r.Flag(), bits)
+		expect(t, ok, v != 0)
+	}
+	expect(t, uint(12), r.At())
+}
+
+func TestWriterMeasure(t *testing.T) {
+	buf := make([]byte, 16)
+	w := NewWriter(buf)
+	w.PutUint32(3, 0)
+	bits := w.Measure(func(w *Writer) {
+		w.PutUint32(7, 0)
+		w.PutBit(true)
+		w.PutUint64(37, 0)
+		w.PutAlignedBytes([]byte{0x01, 0x02})
+	})
+	expect(t, 7+1+37+16, bits)
+	expect(t, 0, w.Measure(func(*Writer) {}))
+	c := NewCountingWriter()
+	expect(t, 64, c.Measure(func(w *Writer) { w.PutBe64(0) }))
+}
+
+func TestFrames(t *testing.T) {
+	for _, channels := range []int{1, 2, 6} {
+		for _, bits := range []uint{8, 12, 16, 20, 24, 32} {
+			var frames [][]int32
+			for i := 0; i < 32; i++ {
+				frame := make([]int32, channels)
+				for j := range frame {
+					frame[j] = int32(rand.Uint32()) >> (32 - bits)
+				}
+				frames = append(frames, frame)
+			}
+			buf := make([]byte, channels*int(bits)*4)
+			w := NewWriter(buf)
+			for _, frame := range frames {
+				w.PutFrame(bits, frame)
+			}
+			expect(t, nil, w.Flush())
+			r := NewReader(buf)
+			for _, frame := range frames {
+				expect(t, frame, r.ReadFrame(channels, bits))
+			}
+			expect(t, uint(0), r.LeftBits())
+		}
+	}
+}
+
+func TestXor(t *testing.T) {
+	buf := make([]byte, 4)
+	w := NewWriter(buf)
+	w.PutXor(4, 0x9, 0xF)
+	w.PutXor(20, 0xABCDE, 0xFFFFFFFFFFF00000)
+	w.PutXor(8, 0x12, 0x12)
+	expect(t, nil, w.Flush())
+	compare(t, buf, []byte{0x6A, 0xBC, 0xDE, 0x00})
+	r := NewReader(buf)
+	expect(t, uint32(0x9), r.ReadXor(4, 0xF))
+	expect(t, uint32(0xABCDE), r.ReadXor(20, 0xFFFFFFFFFFF00000))
+	expect(t, uint32(0x12), r.ReadXor(8, 0x12))
+	r.Reset()
+	expect(t, uint32(0xF9), r.ReadXor(8, 0x93))
+	expect(t, uint32(0xBCDE), r.ReadXor(16, 0x10000))
+}
+
+func TestWriterLimit(t *testing.T) {
+	buf := []byte{0xAA, 0xAA, 0xAA, 0xAA}
+	w := NewWriterLimit(buf, 16)
+	expect(t, 16, w.Bits())
+	w.PutBe16(0)
+	expect(t, nil, w.Flush())
+	expect(t, false, w.CanWrite(1))
+	w.PutByte(0)
+	expect(t, ErrOverflow, w.Flush())
+	compare(t, []byte{0x00, 0x00, 0xAA, 0xAA}, buf)
+	// unaligned limits
+	w = NewWriterLimit(buf, 12)
+	expect(t, 12, w.Bits())
+	w.PutUint32(12, 0xFFF)
+	expect(t, 0, w.Bits())
+	expect(t, ErrUnderflow, w.Flush())
+	w.PutUint32(4, 0)
+	expect(t, ErrOverflow, w.Flush())
+	w = NewWriterLimit(buf, 64)
+	expect(t, 32, w.Bits())
+}
+
+func TestRLE(t *testing.T) {
+	buf := make([]byte, 4)
+	w := NewWriter(buf)
+	w.PutRLE(4, 1, 9, 1)
+	w.PutRLE(6, 13, 63, 0x1ABC)
+	w.PutRLE(8, 0, 3, 0)
+	expect(t, nil, w.Flush())
+	compare(t, buf, []byte{0x9F, 0xFA, 0xBC, 0x03})
+	r := NewReader(buf)
+	count, value := r.RLE(4, 1)
+	expect(t, []uint32{9, 1}, []uint32{count, value})
+	count, value = r.RLE(6, 13)
+	expect(t, []uint32{63, 0x1ABC}, []uint32{count, value})
+	count, value = r.RLE(8, 0)
+	expect(t, []uint32{3, 0}, []uint32{count, value})
+	expect(t, nil, r.Error())
+	r.RLE(1, 0)
+	expect(t, ErrOverflow, r.Error())
+}
+
+func TestCountingWriter(t *testing.T) {
+	src := makeSource(64)
+	dst := make([]byte, len(src))
+	w := NewWriter(dst)
+	c := NewCountingWriter()
+	for _, x := range []*Writer{&w, &c} {
+		x.PutBit(true)
+		x.PutUint32(7, 0x55)
+		x.PutUint64(45, 0x123456789)
+		x.PutLe16(0x1234)
+		expect(t, ErrUnderflow, x.Flush())
+		x.PutReserved(3, true)
+		expect(t, nil, x.Flush())
+		n, err := x.Write(src[:7])
+		expect(t, 7, n)
+		expect(t, nil, err)
+		x.PutUint32(9, 0x1FF)
+	}
+	expect(t, w.Index(), c.Index())
+	expect(t, 0, c.Bits())
+	expect(t, 0, len(c.Bytes()))
+	expect(t, ErrUnderflow, c.Flush())
+	expect(t, w.Index(), c.Index())
+	c.Reset()
+	expect(t, 0, c.Index())
+}
+
+func TestGroups(t *testing.T) {
+	// "foobar" in base64 is "Zm9vYmFy"
+	r := NewReader([]byte("foobar"))
+	expect(t, "Zm9vYmFy", base64Encode(r.ReadGroups(6, 8)))
+	expect(t, nil, r.Error())
+	// "fooba" in base32 is "MZXW6YTB"
+	r = NewReader([]byte("fooba"))
+	groups := r.ReadGroups(5, 8)
+	expect(t, []byte{12, 25, 23, 22, 30, 24, 19, 1}, groups)
+	expect(t, nil, r.Error())
+	dst := make([]byte, 5)
+	w := NewWriter(dst)
+	w.PutGroups(5, groups)
+	expect(t, nil, w.Flush())
+	compare(t, []byte("fooba"), dst)
+	src := makeSource(30)
+	for k := uint(1); k <= 8; k++ {
+		n := len(src) * 8 / int(k)
+		r = NewReader(src)
+		groups = r.ReadGroups(k, n)
+		dst = make([]byte, len(src))
+		w = NewWriter(dst)
+		w.PutGroups(k, groups)
+		w.PutUint32(uint(len(src)*8)-uint(n)*k, 0)
+		expect(t, nil, w.Flush())
+		r = NewReader(dst)
+		expect(t, groups, r.ReadGroups(k, n))
+	}
+}
+
+func base64Encode(groups []byte) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	s := make([]byte, len(groups))
+	for i, v := range groups {
+		s[i] = alphabet[v]
+	}
+	return string(s)
+}
+
+func TestRemaining(t *testing.T) {
+	dst := make([]byte, 8)
+	w := NewWriter(dst)
+	w.PutUint32(3, 0x5)
+	w.PutUint32(10, 0x3FF)
+	rest, offset := w.Remaining()
+	expect(t, uint(5), offset)
+	expect(t, 7, len(rest))
+	expect(t, &dst[1], &rest[0])
+	expect(t, []byte{0xBF, 0xF8}, dst[:2])
+	// hand the remaining bits over to another writer
+	x := NewWriter(rest)
+	x.PutUint32(offset, uint32(rest[0]>>(8-offset)))
+	x.PutUint32(11, 0x7FF)
+	expect(t, nil, x.Flush())
+	expect(t, []byte{0xBF, 0xFF, 0xFF}, dst[:3])
+	w = NewWriter(dst[:1])
+	w.PutUint32(12, 0)
+	rest, offset = w.Remaining()
+	expect(t, uint(4), offset)
+	expect(t, 0, len(rest))
+}
+
+func TestFill(t *testing.T) {
+	for _, skip := range []uint{0, 3, 8, 24, 37} {
+		for _, n := range []int{0, 1, 7, 100} {
+			dst := make([]byte, 128)
+			ref := make([]byte, 128)
+			w := NewWriter(dst)
+			x := NewWriter(ref)
+			w.PutUint32(skip, 0x15)
+			x.PutUint32(skip, 0x15)
+			w.Fill(0xA5, n)
+			for i := 0; i < n; i++ {
+				x.PutByte(0xA5)
+			}
+			expect(t, x.Index(), w.Index())
+			w.PutUint32(7, 0x55)
+			x.PutUint32(7, 0x55)
+			w.PutUint32(uint(-w.Index()&7), 0)
+			x.PutUint32(uint(-x.Index()&7), 0)
+			expect(t, nil, w.Flush())
+			expect(t, nil, x.Flush())
+			compare(t, ref, dst)
+		}
+	}
+	dst := make([]byte, 4)
+	w := NewWriter(dst)
+	w.PutByte(1)
+	w.Fill(0xFF, 4)
+	expect(t, []byte{1, 0xFF, 0xFF, 0xFF}, dst)
+	expect(t, ErrOverflow, w.Flush())
+	w = NewWriterPolicy(dst, OverflowSaturate)
+	w.Fill(0, 5)
+	expect(t, 0, w.Index())
+	expect(t, ErrOverflow, w.Flush())
+	w = NewCountingWriter()
+	w.PutUint32(4, 0)
+	w.Fill(0, 10)
+	expect(t, 84, w.Index())
+}
+
 type WriteBench struct {
 	name string
 	bits int
@@ -269,3 +764,23 @@ func BenchmarkWrites(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkFill(b *testing.B) {
+	buf := make([]byte, 4096)
+	b.Run("byte", func(b *testing.B) {
+		b.SetBytes(int64(len(buf)))
+		for i := 0; i < b.N; i++ {
+			w := NewWriter(buf)
+			for j := 0; j < len(buf); j++ {
+				w.PutByte(0)
+			}
+		}
+	})
+	b.Run("fill", func(b *testing.B) {
+		b.SetBytes(int64(len(buf)))
+		for i := 0; i < b.N; i++ {
+			w := NewWriter(buf)
+			w.Fill(0, len(buf))
+		}
+	})
+}